@@ -0,0 +1,97 @@
+package fov
+
+// Point is a tile coordinate, not a pixel position
+type Point struct {
+	X int
+	Y int
+}
+
+type Set map[Point]bool
+
+func (set Set) Contains(x int, y int) bool {
+	return set[Point{X: x, Y: y}]
+}
+
+// Multipliers that map the first octant onto each of the eight
+var octants = [8][4]int{
+	{1, 0, 0, 1},
+	{0, 1, 1, 0},
+	{0, -1, 1, 0},
+	{-1, 0, 0, 1},
+	{-1, 0, 0, -1},
+	{0, -1, -1, 0},
+	{0, 1, -1, 0},
+	{1, 0, 0, -1},
+}
+
+// Compute returns every tile visible from origin within radius using recursive shadowcasting.
+// Opaque tiles are visible themselves but hide whatever is behind them,
+// opaque should also return true for anything outside the map.
+func Compute(origin Point, radius int, opaque func(x int, y int) bool) Set {
+	visible := make(Set)
+	visible[origin] = true
+	if radius <= 0 {
+		return visible
+	}
+
+	for _, m := range octants {
+		castLight(origin, radius, 1, 1.0, 0.0, m, opaque, visible)
+	}
+	return visible
+}
+
+// Visible checks a single target without keeping the whole set around
+func Visible(origin Point, target Point, radius int, opaque func(x int, y int) bool) bool {
+	dx := target.X - origin.X
+	dy := target.Y - origin.Y
+	if dx*dx+dy*dy > radius*radius {
+		return false
+	}
+	return Compute(origin, radius, opaque)[target]
+}
+
+func castLight(origin Point, radius int, row int, start float64, end float64, m [4]int, opaque func(x int, y int) bool, visible Set) {
+	if start < end {
+		return
+	}
+
+	radiusSq := radius * radius
+	newStart := 0.0
+	for j := row; j <= radius; j++ {
+		dy := -j
+		blocked := false
+		for dx := -j; dx <= 0; dx++ {
+			x := origin.X + dx*m[0] + dy*m[1]
+			y := origin.Y + dx*m[2] + dy*m[3]
+			leftSlope := (float64(dx) - 0.5) / (float64(dy) + 0.5)
+			rightSlope := (float64(dx) + 0.5) / (float64(dy) - 0.5)
+
+			if start < rightSlope {
+				continue
+			} else if end > leftSlope {
+				break
+			}
+
+			if dx*dx+dy*dy <= radiusSq {
+				visible[Point{X: x, Y: y}] = true
+			}
+
+			if blocked {
+				if opaque(x, y) {
+					newStart = rightSlope
+					continue
+				}
+				blocked = false
+				start = newStart
+			} else if opaque(x, y) && j < radius {
+				// Scan the part of the next row that is still lit before the wall
+				blocked = true
+				castLight(origin, radius, j+1, start, leftSlope, m, opaque, visible)
+				newStart = rightSlope
+			}
+		}
+		if blocked {
+			break
+		}
+	}
+}
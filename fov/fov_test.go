@@ -0,0 +1,175 @@
+package fov
+
+import (
+	"strings"
+	"testing"
+)
+
+// Fixture maps: '#' is a wall, '.' floor and 'o' the origin.
+// Expected maps mark visible tiles with 'v' and hidden ones with ' '.
+type fixture struct {
+	tiles  []string
+	origin Point
+}
+
+func parseFixture(t *testing.T, rows string) fixture {
+	f := fixture{tiles: strings.Split(strings.Trim(rows, "\n"), "\n")}
+	found := false
+	for y, row := range f.tiles {
+		if x := strings.IndexByte(row, 'o'); x >= 0 {
+			f.origin = Point{X: x, Y: y}
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("fixture has no origin")
+	}
+	return f
+}
+
+func (f fixture) opaque(x int, y int) bool {
+	if y < 0 || y >= len(f.tiles) || x < 0 || x >= len(f.tiles[y]) {
+		return true
+	}
+	return f.tiles[y][x] == '#'
+}
+
+func render(f fixture, visible Set) string {
+	var rows []string
+	for y, row := range f.tiles {
+		line := []byte(strings.Repeat(" ", len(row)))
+		for x := range row {
+			if visible.Contains(x, y) {
+				line[x] = 'v'
+			}
+		}
+		rows = append(rows, string(line))
+	}
+	return strings.Join(rows, "\n")
+}
+
+func checkFixture(t *testing.T, name string, rows string, radius int, expected string) {
+	t.Helper()
+	f := parseFixture(t, rows)
+	got := render(f, Compute(f.origin, radius, f.opaque))
+	want := strings.Trim(expected, "\n")
+	if got != want {
+		t.Errorf("%v:\ngot\n%v\nwant\n%v", name, got, want)
+	}
+}
+
+func TestOpenRoom(t *testing.T) {
+	checkFixture(t, "open room", `
+#######
+#.....#
+#.....#
+#..o..#
+#.....#
+#.....#
+#######
+`, 10, `
+vvvvvvv
+vvvvvvv
+vvvvvvv
+vvvvvvv
+vvvvvvv
+vvvvvvv
+vvvvvvv
+`)
+}
+
+func TestRadius(t *testing.T) {
+	checkFixture(t, "radius", `
+.........
+.........
+.........
+.........
+....o....
+.........
+.........
+.........
+.........
+`, 2, `
+         
+         
+    v    
+   vvv   
+  vvvvv  
+   vvv   
+    v    
+         
+         
+`)
+}
+
+func TestPillarCastsShadow(t *testing.T) {
+	checkFixture(t, "pillar", `
+...........
+...........
+...........
+...........
+...........
+...........
+.....#.....
+.....o.....
+`, 12, `
+           
+v         v
+vv       vv
+vvv     vvv
+vvvv   vvvv
+vvvvv vvvvv
+vvvvvvvvvvv
+vvvvvvvvvvv
+`)
+}
+
+func TestCorridorCorner(t *testing.T) {
+	checkFixture(t, "corner", `
+#######
+#o....#
+#####.#
+    #.#
+    #.#
+`, 10, `
+vvvvvvv
+vvvvvvv
+vvvvvvv
+       
+       
+`)
+}
+
+func TestWallsStopSight(t *testing.T) {
+	checkFixture(t, "wall", `
+.....
+#####
+..o..
+#####
+.....
+`, 10, `
+     
+vvvvv
+vvvvv
+vvvvv
+     
+`)
+}
+
+func TestVisible(t *testing.T) {
+	f := parseFixture(t, `
+#####
+#o#.#
+#...#
+#####
+`)
+	if !Visible(f.origin, Point{X: 2, Y: 2}, 5, f.opaque) {
+		t.Error("expected diagonal neighbour to be visible")
+	}
+	if Visible(f.origin, Point{X: 3, Y: 1}, 5, f.opaque) {
+		t.Error("expected tile behind the wall to be hidden")
+	}
+	if Visible(f.origin, Point{X: 3, Y: 2}, 1, f.opaque) {
+		t.Error("expected tile outside the radius to be hidden")
+	}
+}
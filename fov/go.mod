@@ -0,0 +1,3 @@
+module fov
+
+go 1.17
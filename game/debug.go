@@ -2,8 +2,9 @@ package game
 
 import (
 	"fmt"
-	"rendering"
-	"utils"
+	"math/rand"
+	"sim"
+	"time"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
@@ -12,6 +13,7 @@ type DebugDisplayData struct {
 	Enabled         bool
 	TileDisplayMode int
 	TileLightFx     bool
+	EnemyPath       bool
 }
 
 const (
@@ -20,70 +22,79 @@ const (
 	DD_TILE_DISTANCE_FROM_PLAYER = iota
 )
 
-func handleTileDebugDisplay(tile *Tile) {
-	switch state.UIState.DebugDisplay.TileDisplayMode {
+func (game *GameState) handleTileDebugDisplay(tile *sim.Tile) {
+	switch game.UIState.DebugDisplay.TileDisplayMode {
 	case DD_TILE_LIGHT:
 		//! Tile light debug display
 		rl.DrawText(fmt.Sprintf("%d", tile.LightLevel), tile.Pos.X, tile.Pos.Y, 12, rl.Red)
 	case DD_TILE_DISTANCE_FROM_PLAYER:
 		//! Tile distance debug display
-		dist := tile.DistanceToPlayer()
+		dist := tile.DistanceToPlayer(game.World)
 		rl.DrawText(fmt.Sprintf("%.1f", dist), tile.Pos.X, tile.Pos.Y, 12, rl.Red)
 	}
 
 }
 
-func drawDebugSettings() {
-	if rendering.DrawButton(rl.NewVector2(100.0, 100.0), "No display") {
-		state.UIState.DebugDisplay.TileDisplayMode = DD_TILE_NO_DISPLAY
+func (game *GameState) drawDebugSettings() {
+	if game.Renderer.DrawButton(rl.NewVector2(100.0, 100.0), "No display") {
+		game.UIState.DebugDisplay.TileDisplayMode = DD_TILE_NO_DISPLAY
 	}
 
-	if rendering.DrawButton(rl.NewVector2(100.0, 130.0), "Tile light level") {
-		state.UIState.DebugDisplay.TileDisplayMode = DD_TILE_LIGHT
+	if game.Renderer.DrawButton(rl.NewVector2(100.0, 130.0), "Tile light level") {
+		game.UIState.DebugDisplay.TileDisplayMode = DD_TILE_LIGHT
 	}
 
-	if rendering.DrawButton(rl.NewVector2(100.0, 160.0), "Tile distance from player") {
-		state.UIState.DebugDisplay.TileDisplayMode = DD_TILE_DISTANCE_FROM_PLAYER
+	if game.Renderer.DrawButton(rl.NewVector2(100.0, 160.0), "Tile distance from player") {
+		game.UIState.DebugDisplay.TileDisplayMode = DD_TILE_DISTANCE_FROM_PLAYER
 	}
 
-	if rendering.DrawButton(rl.NewVector2(100.0, 190.0), "Teleport to cursor") {
-		state.Player.Pos = state.UIState.SelectionMode.Pos
+	if game.Renderer.DrawButton(rl.NewVector2(100.0, 190.0), "Teleport to cursor") {
+		game.World.Player.Pos = game.World.Selection.Pos
 	}
 
-	if rendering.DrawButton(rl.NewVector2(100.0, 220.0), "Spawn enemy on cursor") {
-		nEnemy := CreateRandomEnemy(state.UIState.SelectionMode.Pos)
-		state.Enemies = append(state.Enemies, nEnemy)
+	if game.Renderer.DrawButton(rl.NewVector2(100.0, 220.0), "Spawn enemy on cursor") {
+		// Not from the world's random numbers, so that debug spawns don't change what the game rolls
+		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		if archetype := game.World.Data.NewSpawnTable(game.World.Dungeon.Depth).Pick(rng); archetype != nil {
+			nEnemy := archetype.NewEnemy(game.World.Selection.Pos, game.World.Dungeon.Depth)
+			game.World.Enemies = append(game.World.Enemies, nEnemy)
+		}
+	}
+
+	if game.Renderer.DrawButton(rl.NewVector2(100.0, 250.0), "Toggle light fx") {
+		game.UIState.DebugDisplay.TileLightFx = !game.UIState.DebugDisplay.TileLightFx
 	}
 
-	if rendering.DrawButton(rl.NewVector2(100.0, 250.0), "Toggle light fx") {
-		state.UIState.DebugDisplay.TileLightFx = !state.UIState.DebugDisplay.TileLightFx
+	if game.Renderer.DrawButton(rl.NewVector2(100.0, 280.0), "Toggle selected enemy path") {
+		game.UIState.DebugDisplay.EnemyPath = !game.UIState.DebugDisplay.EnemyPath
 	}
 }
 
-func drawDebugInfo() {
-	tileDebugInfo()
-	enemiesDebugInfo()
+func (game *GameState) drawDebugInfo() {
+	game.tileDebugInfo()
+	game.enemiesDebugInfo()
 
 	rl.DrawText(fmt.Sprintf("%.2f fps", rl.GetFPS()), 50, 20, 24, rl.RayWhite)
 	rl.DrawText(fmt.Sprintf("%.4f ms", rl.GetFrameTime()*1000.0), 50, 50, 24, rl.RayWhite)
+	rl.DrawText(fmt.Sprintf("seed %d", game.World.Seed), 300, 20, 24, rl.RayWhite)
 }
 
-func tileDebugInfo() {
-	var tile *Tile
-	var sourcePos utils.IVector2
-	if state.UIState.SelectionMode.Using {
-		sourcePos = state.UIState.SelectionMode.Pos
+func (game *GameState) tileDebugInfo() {
+	var tile *sim.Tile
+	var sourcePos sim.IVector2
+	if game.World.Selection.Using {
+		sourcePos = game.World.Selection.Pos
 	} else {
-		sourcePos = state.Player.Pos
+		sourcePos = game.World.Player.Pos
 	}
 
-	if t, ok := GetMapTile(sourcePos); ok {
+	if t, ok := game.World.GetMapTile(sourcePos); ok {
 		tile = t
 	}
 
-	background := rl.NewRectangle(50.0, 280.0, 250.0, 180.0)
+	background := rl.NewRectangle(50.0, 310.0, 250.0, 180.0)
 
-	pos := utils.IVector2{
+	pos := sim.IVector2{
 		X: tile.Pos.X / TILE_SIZE,
 		Y: tile.Pos.Y / TILE_SIZE,
 	}
@@ -93,7 +104,7 @@ func tileDebugInfo() {
 	rl.DrawRectangleRec(background, rl.DarkGray)
 
 	rl.DrawTextRec(
-		state.AppState.RenderAssets.SecondaryFont,
+		game.Renderer.Assets.SecondaryFont,
 		data,
 		background,
 		24.0,
@@ -103,17 +114,17 @@ func tileDebugInfo() {
 	)
 }
 
-func enemiesDebugInfo() {
+func (game *GameState) enemiesDebugInfo() {
 	enemyCount := 0
-	var closestEnemy *Enemy
+	var closestEnemy *sim.Enemy
 
-	for _, enemy := range state.Enemies {
+	for _, enemy := range game.World.Enemies {
 		if enemy != nil {
 			enemyCount++
 			if closestEnemy == nil {
 				closestEnemy = enemy
 			} else {
-				if closestEnemy.DistanceToPlayer() > enemy.DistanceToPlayer() {
+				if closestEnemy.DistanceToPlayer(game.World) > enemy.DistanceToPlayer(game.World) {
 					closestEnemy = enemy
 				}
 			}
@@ -121,22 +132,22 @@ func enemiesDebugInfo() {
 	}
 
 	if closestEnemy != nil {
-		pos := utils.IVector2{
+		pos := sim.IVector2{
 			X: closestEnemy.Pos.X / TILE_SIZE,
 			Y: closestEnemy.Pos.Y / TILE_SIZE,
 		}
 
-		p_pos := utils.IVector2{
+		p_pos := sim.IVector2{
 			X: closestEnemy.LastKnownPlayerPos.X / TILE_SIZE,
 			Y: closestEnemy.LastKnownPlayerPos.Y / TILE_SIZE,
 		}
-		data := fmt.Sprintf("Enemies in level: %v\nClosest Enemy: %.1f\nPos: %v\nLast player pos: %v", enemyCount, closestEnemy.DistanceToPlayer(), pos, p_pos)
+		data := fmt.Sprintf("Enemies in level: %v\nClosest Enemy: %v (%v) %.1f\nPos: %v\nLast player pos: %v", enemyCount, closestEnemy.Kind, closestEnemy.Behaviour, closestEnemy.DistanceToPlayer(game.World), pos, p_pos)
 
-		background := rl.NewRectangle(50.0, state.AppState.Settings.Resolution.ToVec2().Y-350.0, 250.0, 180.0)
+		background := rl.NewRectangle(50.0, game.AppState.Settings.Resolution.ToVec2().Y-350.0, 250.0, 180.0)
 		rl.DrawRectangleRec(background, rl.DarkGray)
 
 		rl.DrawTextRec(
-			state.AppState.RenderAssets.SecondaryFont,
+			game.Renderer.Assets.SecondaryFont,
 			data,
 			background,
 			24.0,
@@ -0,0 +1,158 @@
+package game
+
+import (
+	"rendering"
+	"sim"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Tint for explored tiles that are out of the player's sight
+var FOG_OF_WAR_TINT = rl.NewColor(70, 70, 90, 255)
+
+func toVec2(pos sim.IVector2) rl.Vector2 {
+	return rl.Vector2{X: float32(pos.X), Y: float32(pos.Y)}
+}
+
+func (game *GameState) drawTile(tile *sim.Tile) {
+	tileType := tile.Type
+	colour := rl.White
+
+	if tile.LightLevel == 0 {
+		tileType = tile.RememberedType
+		colour = FOG_OF_WAR_TINT
+	} else if game.UIState.DebugDisplay.TileLightFx {
+		colour.A = tile.LightLevel
+	}
+	texture := game.Renderer.GetTile(tileType)
+
+	if tileType == sim.TILE_WALL_STONE {
+		tile.UpdateNeighbours(game.World)
+
+		rl.DrawTexture(*game.Renderer.GetTileSet(game.World.TileSet).GetTexture(tile.Neighbours, game.Renderer.Assets.MissingTexture), tile.Pos.X, tile.Pos.Y, colour)
+	} else {
+		rl.DrawTexture(*texture, tile.Pos.X, tile.Pos.Y, colour)
+	}
+}
+
+func (game *GameState) drawCharacter(character sim.Character) {
+	pos := character.GetPos()
+	texture := game.Renderer.GetCharacterSprite(character.GetSprite())
+	rl.DrawTexture(*texture, pos.X, pos.Y, rl.White)
+	drawEffectIcons(pos, *character.GetEffects())
+}
+
+// Effects have no icons of their own yet, they are drawn as coloured pips like items
+var EFFECT_COLOURS = map[sim.EffectKind]rl.Color{
+	sim.EFFECT_POISON: rl.Green,
+	sim.EFFECT_STUN:   rl.Yellow,
+	sim.EFFECT_BLEED:  rl.Maroon,
+	sim.EFFECT_HASTE:  rl.SkyBlue,
+	sim.EFFECT_BLIND:  rl.DarkGray,
+}
+
+func effectColour(kind sim.EffectKind) rl.Color {
+	if colour, ok := EFFECT_COLOURS[kind]; ok {
+		return colour
+	}
+	return rl.Pink
+}
+
+func drawEffectIcon(bounds rl.Rectangle, effect sim.Effect) {
+	rl.DrawRectangleRounded(bounds, 0.5, 2, effectColour(effect.Kind))
+	rl.DrawRectangleRoundedLines(bounds, 0.5, 2, 1.0, rl.Black)
+}
+
+// A row of pips along the top of the character's tile, one per effect
+func drawEffectIcons(pos sim.IVector2, effects sim.Effects) {
+	const size = 7
+	for i, effect := range effects {
+		drawEffectIcon(rl.NewRectangle(float32(pos.X+1+int32(i)*(size+1)), float32(pos.Y-size/2), size, size), effect)
+	}
+}
+
+// There are no item sprites yet, items are told apart by the colour of their category
+var ITEM_COLOURS = map[sim.ItemCategory]rl.Color{
+	sim.ITEM_WEAPON:       rl.LightGray,
+	sim.ITEM_ARMOUR:       rl.SkyBlue,
+	sim.ITEM_CONSUMABLE:   rl.Red,
+	sim.ITEM_KEY:          rl.Gold,
+	sim.ITEM_DIGGING_TOOL: rl.Brown,
+	sim.ITEM_TRINKET:      rl.Purple,
+}
+
+func itemColour(item *sim.Item) rl.Color {
+	if colour, ok := ITEM_COLOURS[item.Category]; ok {
+		return colour
+	}
+	return rl.Pink
+}
+
+func (game *GameState) drawItem(item *sim.FloorItem) {
+	const size = TILE_SIZE / 3
+	bounds := rl.NewRectangle(float32(item.Pos.X+(TILE_SIZE-size)/2), float32(item.Pos.Y+(TILE_SIZE-size)/2), float32(size), float32(size))
+	rl.DrawRectangleRounded(bounds, 0.3, 2, itemColour(item.Item))
+	rl.DrawRectangleRoundedLines(bounds, 0.3, 2, 1.0, rl.Black)
+}
+
+func (game *GameState) selectedEnemy() *sim.Enemy {
+	for _, enemy := range game.World.Enemies {
+		if enemy.Pos == game.World.Selection.Pos {
+			return enemy
+		}
+	}
+	return nil
+}
+
+func (game *GameState) drawEnemyPathDebug() {
+	enemy := game.selectedEnemy()
+	if enemy == nil || len(enemy.Path) == 0 {
+		return
+	}
+
+	half := TILE_SIZE / 2
+	prev := enemy.Pos
+	for _, pos := range enemy.Path {
+		rl.DrawLine(prev.X+half, prev.Y+half, pos.X+half, pos.Y+half, rl.Orange)
+		rl.DrawRectangle(pos.X+half-3, pos.Y+half-3, 6, 6, rl.Orange)
+		prev = pos
+	}
+}
+
+// Previews the selected ability while the cursor is in use: the tiles in range around the player,
+// and the tiles it would hit around the cursor, gold if it can be used there and red if not
+func (game *GameState) drawAbilityPreview() {
+	world := game.World
+	ability := world.Player.Abilities.SelectedAbility(world.Data.Abilities)
+	if !world.Selection.Using || ability == nil || world.Player.Turn.Done {
+		return
+	}
+
+	for _, pos := range world.TilesWithin(world.Player.Pos, ability.Range) {
+		rl.DrawRectangle(pos.X, pos.Y, TILE_SIZE, TILE_SIZE, rl.ColorAlpha(rendering.SilverAccent, 0.15))
+	}
+
+	colour := rendering.GoldAccent
+	if sim.NewAbilityCommand(world).Validate(world) != nil {
+		colour = rl.Red
+	}
+	area := []sim.IVector2{world.Selection.Pos}
+	if ability.Radius > 0 {
+		area = world.TilesWithin(world.Selection.Pos, ability.Radius)
+	}
+	for _, pos := range area {
+		rl.DrawRectangle(pos.X, pos.Y, TILE_SIZE, TILE_SIZE, rl.ColorAlpha(colour, 0.3))
+		rl.DrawRectangleLines(pos.X, pos.Y, TILE_SIZE, TILE_SIZE, rl.ColorAlpha(colour, 0.6))
+	}
+}
+
+// Burning flares are drawn where they landed, the light they give is in the tiles
+func (game *GameState) drawFlares() {
+	half := TILE_SIZE / 2
+	for _, flare := range game.World.Flares {
+		if tile, ok := game.World.GetMapTile(flare.Pos); ok && tile.LightLevel > 0 {
+			rl.DrawCircle(flare.Pos.X+half, flare.Pos.Y+half, 5.0, rl.Yellow)
+			rl.DrawCircleLines(flare.Pos.X+half, flare.Pos.Y+half, 7.0, rl.Orange)
+		}
+	}
+}
@@ -1,50 +1,97 @@
 package game
 
 import (
+	"fmt"
+	"log"
 	"math"
 	"rendering"
+	"sim"
 	"utils"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
-const TILE_SIZE int32 = 32
-const PLAYER_OFFSET_X int32 = 0
-const PLAYER_OFFSET_Y int32 = 0
+const TILE_SIZE = sim.TILE_SIZE
 
+// GameState is the raylib front-end of a game, the game itself lives in World
 type GameState struct {
 	AppState *utils.State
+	Renderer *rendering.Renderer
 	Camera   *rl.Camera2D
-	Player   *Player
-	Map      [][]*Tile
-	Enemies  []*Enemy
+	World    *sim.World
 	UIState  UIState
 
-	tempTimeSinceTurn float32
+	input sim.InputSource
+	// Quickloaded during the frame, swapped in once the frame is done
+	loaded *sim.World
 }
 
-var state GameState
-
-func InitGame(appState *utils.State) *GameState {
-	player, cam := initPlayerAndCam(appState)
-	state = GameState{
-		AppState:          appState,
-		Player:            player,
-		Camera:            cam,
-		Map:               nil,
-		UIState:           NewUIState(player),
-		tempTimeSinceTurn: 0.0,
+func NewGameState(appState *utils.State, renderer *rendering.Renderer, world *sim.World, input sim.InputSource) *GameState {
+	appState.ActiveSeed = world.Seed
+	if world.TileSet != "" && !rendering.TileSetExists(world.TileSet) {
+		log.Printf("Unknown tileset %q, using %v", world.TileSet, rendering.DEFAULT_TILESET)
+		world.TileSet = ""
 	}
 
-	state.Map, state.Enemies = GenerateLevel()
-	return &state
+	return &GameState{
+		AppState: appState,
+		Renderer: renderer,
+		Camera:   newCamera(appState),
+		World:    world,
+		UIState:  NewUIState(),
+		input:    input,
+	}
 }
 
-func initPlayerAndCam(state *utils.State) (*Player, *rl.Camera2D) {
-	cam := rl.Camera2D{
+func ConfigFromSettings(settings *utils.Settings, data *sim.Data) sim.Config {
+	return sim.Config{
+		Seed:         settings.Seed,
+		LevelPath:    settings.LevelPath,
+		Generator:    settings.Generator,
+		MapOverrides: settings.MapOverrides,
+		Data:         data,
+	}
+}
+
+// Applies the settings the replay was recorded with
+func ApplyReplay(replay *sim.Replay, settings *utils.Settings) {
+	settings.Seed = replay.Seed
+	settings.SeedInput = fmt.Sprint(replay.Seed)
+	settings.LevelPath = replay.LevelPath
+	settings.Generator = replay.Generator
+	settings.MapOverrides = replay.MapOverrides
+	settings.ReplayPath = replay.Path
+}
+
+func newInputSource(appState *utils.State, world *sim.World) sim.InputSource {
+	var input sim.InputSource = KeyboardInput{}
+	if appState.Settings.ReplayPath != "" {
+		if replay, err := sim.LoadReplay(appState.Settings.ReplayPath); err == nil {
+			log.Printf("Playing back %d inputs from %v", len(replay.Inputs), replay.Path)
+			input = &sim.ReplayInput{Inputs: replay.Inputs}
+		} else {
+			log.Println("Couldn't load replay: ", err)
+			appState.Settings.ReplayPath = ""
+		}
+	}
+
+	if appState.Settings.RecordPath != "" {
+		config := ConfigFromSettings(&appState.Settings, world.Data)
+		config.Seed = world.Seed
+		if recorder, err := sim.NewReplayRecorder(appState.Settings.RecordPath, input, config); err == nil {
+			input = recorder
+		} else {
+			log.Println("Couldn't record replay: ", err)
+		}
+	}
+	return input
+}
+
+func newCamera(appState *utils.State) *rl.Camera2D {
+	return &rl.Camera2D{
 		Offset: rl.Vector2{
-			X: float32(state.Settings.Resolution.X / 2),
-			Y: float32(state.Settings.Resolution.Y / 2),
+			X: float32(appState.Settings.Resolution.X / 2),
+			Y: float32(appState.Settings.Resolution.Y / 2),
 		},
 		Target: rl.Vector2{
 			X: 0.0,
@@ -53,115 +100,105 @@ func initPlayerAndCam(state *utils.State) (*Player, *rl.Camera2D) {
 		Rotation: 0.0,
 		Zoom:     1.0,
 	}
+}
 
-	player := Player{
-		Pos:   utils.IVector2{X: PLAYER_OFFSET_X, Y: PLAYER_OFFSET_Y},
-		State: rendering.PLAYER_IDLE,
-		Stats: Stats{
-			Movement:   6,
-			Visibility: 8,
-			Vitality:   6,
-			Strength:   6,
-			Dexterity:  6,
-		},
-		Turn: TurnData{
-			Movement: 6,
-			Actions:  3,
-			Done:     false,
-		},
+func (game *GameState) autosave() {
+	if err := game.World.SaveGame(utils.SAVE_FILE); err != nil {
+		log.Println("Autosave failed: ", err)
 	}
+}
 
-	return &player, &cam
+func (game *GameState) Poll() sim.Input {
+	input := game.input.Poll()
+	game.HandleControls(input)
+	return input
 }
 
-func GameUpdate(appState *utils.State, gameState **GameState) {
-	if *gameState == nil {
-		if !appState.Loading {
-			appState.Loading = true
+func (game *GameState) HandleEvent(event sim.Event) {
+	replaying := game.AppState.Settings.ReplayPath != ""
 
-			go func() {
-				*gameState = InitGame(appState)
-				appState.Loading = false
-			}()
+	switch event.Kind {
+	case sim.EVENT_PLAYER_DIED:
+		if !replaying {
+			sim.DeleteSave(utils.SAVE_FILE)
 		}
-	} else {
-		HandleControls()
-
-		if state.Player.Turn.Done {
-			for _, enemy := range state.Enemies {
-				if !enemy.Turn.Done {
-					go enemy.DoAction()
-				}
-			}
+		game.AppState.View = utils.GAME_OVER
+	case sim.EVENT_ROUND_ENDED:
+		if !game.World.Player.IsDead() && !replaying {
+			game.autosave()
 		}
+	}
+}
 
-		var enemiesToDraw []*Enemy
-		for i, enemy := range state.Enemies {
-			if enemy.Health <= 0.0 {
-				length := len(state.Enemies)
-				if length > 0 {
-					state.Enemies[i] = state.Enemies[length-1]
-					state.Enemies = state.Enemies[:length-1]
-				}
-			}
-			if enemy.VisibleToPlayer() {
-				enemy.LightLevel = calculateLightLevel(enemy.DistanceToPlayer(), state.Player.Stats.Visibility)
-				enemiesToDraw = append(enemiesToDraw, enemy)
-			}
-		}
+// Update runs a frame of the game and draws it
+func (game *GameState) Update() {
+	if replay, ok := game.input.(*sim.ReplayInput); ok && replay.Done() {
+		log.Printf("Replay finished, state hash %016x", game.World.StateHash())
+		game.AppState.Settings.ReplayPath = ""
+		game.input = KeyboardInput{}
+	}
 
-		//*
-		//*	Filter out the tiles that are visible to the player
-		//*	If the the tile is visible push it to a separate array
-		//*	that the renderer can use to save time not going through all this at render time
-		//*
-		var tilesToDraw []*Tile
-
-		for _, tile_row := range state.Map {
-			for _, tile := range tile_row {
-				if tile != nil {
-					//! Check if tile coordinates are in the player's visibility range
-					//! If not, don't bother adding it for render
-					if tile.VisibleToPlayer(&enemiesToDraw) {
-						tilesToDraw = append(tilesToDraw, tile)
-					}
-				}
-			}
-		}
+	game.World.Step(game)
+	if game.loaded != nil {
+		game.World = game.loaded
+		game.AppState.ActiveSeed = game.World.Seed
+		game.loaded = nil
+	}
+	game.draw()
+}
 
-		rl.BeginDrawing()
+func (game *GameState) draw() {
+	world := game.World
+	game.Camera.Target = toVec2(world.Player.Pos)
 
-		//*
-		//*	Draw 2D objects
-		//*	Characters, tiles etc.
-		//*
-		rl.BeginMode2D(*state.Camera)
-		rl.ClearBackground(rl.Black)
+	zoomMult := float32(rl.GetMouseWheelMove()) * 0.1
+	if game.Camera.Zoom+zoomMult > 0.2 {
+		game.Camera.Zoom += zoomMult
+	}
 
-		for _, tile := range tilesToDraw {
-			tile.Draw()
+	rl.BeginDrawing()
 
-			if state.UIState.DebugDisplay.Enabled {
-				handleTileDebugDisplay(tile)
-			}
-		}
+	//*
+	//*	Draw 2D objects
+	//*	Characters, tiles etc.
+	//*
+	rl.BeginMode2D(*game.Camera)
+	rl.ClearBackground(rl.Black)
 
-		for _, enemy := range enemiesToDraw {
-			enemy.Draw()
+	for _, tile := range world.KnownTiles {
+		game.drawTile(tile)
+
+		if game.UIState.DebugDisplay.Enabled {
+			game.handleTileDebugDisplay(tile)
 		}
+	}
 
-		state.Player.Draw()
-		drawSelectionCursor()
+	game.drawAbilityPreview()
+	game.drawFlares()
 
-		rl.EndMode2D()
+	for _, item := range world.VisibleItems {
+		game.drawItem(item)
+	}
 
-		//*
-		//*	UI Section
-		//*
-		drawUI()
+	for _, enemy := range world.VisibleEnemies {
+		game.drawCharacter(enemy)
+	}
 
-		rl.EndDrawing()
+	if game.UIState.DebugDisplay.Enabled && game.UIState.DebugDisplay.EnemyPath {
+		game.drawEnemyPathDebug()
 	}
+
+	game.drawCharacter(world.Player)
+	game.drawSelectionCursor()
+
+	rl.EndMode2D()
+
+	//*
+	//*	UI Section
+	//*
+	game.drawUI()
+
+	rl.EndDrawing()
 }
 
 func reverseRange(v float32) float32 {
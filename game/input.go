@@ -0,0 +1,57 @@
+package game
+
+import (
+	"sim"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+type keyBinding struct {
+	Action sim.Input
+	Keys   []int32
+}
+
+var keyBindings = []keyBinding{
+	{sim.INPUT_LEFT, []int32{rl.KeyLeft, rl.KeyA}},
+	{sim.INPUT_RIGHT, []int32{rl.KeyRight, rl.KeyD}},
+	{sim.INPUT_UP, []int32{rl.KeyUp, rl.KeyW}},
+	{sim.INPUT_DOWN, []int32{rl.KeyDown, rl.KeyS}},
+	{sim.INPUT_SELECT, []int32{rl.KeySpace}},
+	{sim.INPUT_DIG, []int32{rl.KeyB}},
+	{sim.INPUT_ATTACK, []int32{rl.KeyV}},
+	{sim.INPUT_STAIRS, []int32{rl.KeyE}},
+	{sim.INPUT_END_TURN, []int32{rl.KeyEnter}},
+	{sim.INPUT_CHARACTER_PANEL, []int32{rl.KeyC}},
+	{sim.INPUT_PAUSE, []int32{rl.KeyM, rl.KeyEscape}},
+	{sim.INPUT_QUICKSAVE, []int32{rl.KeyF5}},
+	{sim.INPUT_QUICKLOAD, []int32{rl.KeyF9}},
+	{sim.INPUT_DEBUG_DISPLAY, []int32{rl.KeyF1}},
+	{sim.INPUT_VISIBILITY_UP, []int32{rl.KeyI}},
+	{sim.INPUT_VISIBILITY_DOWN, []int32{rl.KeyK}},
+	{sim.INPUT_UNDO, []int32{rl.KeyZ, rl.KeyBackspace}},
+	{sim.INPUT_PICK_UP, []int32{rl.KeyG}},
+	{sim.INPUT_DROP, []int32{rl.KeyX}},
+	{sim.INPUT_USE_ITEM, []int32{rl.KeyQ}},
+	{sim.INPUT_NEXT_ITEM, []int32{rl.KeyRightBracket}},
+	{sim.INPUT_PREVIOUS_ITEM, []int32{rl.KeyLeftBracket}},
+	{sim.INPUT_INVENTORY_PANEL, []int32{rl.KeyTab}},
+	{sim.INPUT_EQUIP, []int32{rl.KeyR}},
+	{sim.INPUT_NEXT_STAT, []int32{rl.KeyN}},
+	{sim.INPUT_RAISE_STAT, []int32{rl.KeyL}},
+	{sim.INPUT_NEXT_ABILITY, []int32{rl.KeyT}},
+	{sim.INPUT_USE_ABILITY, []int32{rl.KeyF}},
+}
+
+type KeyboardInput struct{}
+
+func (keyboard KeyboardInput) Poll() sim.Input {
+	var input sim.Input
+	for _, binding := range keyBindings {
+		for _, key := range binding.Keys {
+			if rl.IsKeyPressed(key) {
+				input |= binding.Action
+			}
+		}
+	}
+	return input
+}
@@ -0,0 +1,63 @@
+package game
+
+import (
+	"log"
+	"rendering"
+	"sim"
+	"utils"
+)
+
+// Loader sets a game up in the background while the main loop draws the loading screen.
+// The goroutine only builds the world, the app state is left to the main loop.
+type Loader struct {
+	appState *utils.State
+	renderer *rendering.Renderer
+	done     chan loadedWorld
+}
+
+type loadedWorld struct {
+	world *sim.World
+	// Continued from the save rather than started anew
+	continued bool
+}
+
+// StartLoading continues the saved game if the app state asks for it, otherwise starts a new one, both with data
+func StartLoading(appState *utils.State, renderer *rendering.Renderer, data *sim.Data) *Loader {
+	loader := Loader{
+		appState: appState,
+		renderer: renderer,
+		done:     make(chan loadedWorld, 1),
+	}
+	continueGame := appState.ContinueGame
+	config := ConfigFromSettings(&appState.Settings, data)
+	appState.ContinueGame = false
+	appState.Loading = true
+
+	go func() {
+		if continueGame {
+			world, err := sim.LoadGame(utils.SAVE_FILE, data)
+			if err == nil {
+				loader.done <- loadedWorld{world: world, continued: true}
+				return
+			}
+			log.Println("Couldn't continue, starting a new game instead: ", err)
+		}
+		loader.done <- loadedWorld{world: sim.NewWorld(config)}
+	}()
+	return &loader
+}
+
+// Done hands over the game once it is loaded, without waiting for it
+func (loader *Loader) Done() (*GameState, bool) {
+	select {
+	case loaded := <-loader.done:
+		var input sim.InputSource = KeyboardInput{}
+		if !loaded.continued {
+			input = newInputSource(loader.appState, loaded.world)
+		}
+		loader.appState.Loading = false
+		return NewGameState(loader.appState, loader.renderer, loaded.world, input), true
+	default:
+		return nil, false
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"rendering"
+	"sim"
 	"utils"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
@@ -11,42 +12,36 @@ import (
 
 type UIState struct {
 	CharacterPanelOpen bool
-	SelectionMode      SelectionMode
+	InventoryPanelOpen bool
 	DebugDisplay       DebugDisplayData
 }
 
-type SelectionMode struct {
-	Using bool
-	Pos   utils.IVector2
-}
-
-func NewUIState(player *Player) UIState {
+func NewUIState() UIState {
 	return UIState{
 		CharacterPanelOpen: false,
+		InventoryPanelOpen: false,
 		DebugDisplay: DebugDisplayData{
 			Enabled:         false,
 			TileDisplayMode: DD_TILE_NO_DISPLAY,
 			TileLightFx:     true,
-		},
-		SelectionMode: SelectionMode{
-			Using: false,
-			Pos:   player.Pos,
+			EnemyPath:       true,
 		},
 	}
 }
 
-func drawSelectionCursor() {
-	if state.UIState.SelectionMode.Using {
+func (game *GameState) drawSelectionCursor() {
+	if game.World.Selection.Using {
 		alpha := float32((math.Cos(3.0*float64(rl.GetTime())) + 1) * 0.5)
-		rl.DrawTexture(*rendering.GetUISprite(rendering.SPRITE_SELECTION_MARK), state.UIState.SelectionMode.Pos.X, state.UIState.SelectionMode.Pos.Y, rl.ColorAlpha(rl.White, alpha))
+		rl.DrawTexture(*game.Renderer.GetUISprite(rendering.SPRITE_SELECTION_MARK), game.World.Selection.Pos.X, game.World.Selection.Pos.Y, rl.ColorAlpha(rl.White, alpha))
 	}
 }
 
-func drawCharacterPanel() {
+func (game *GameState) drawCharacterPanel() {
 	xPos := float32(25.0)
-	yPos := state.AppState.Settings.Resolution.ToVec2().Y/2.0 - 250.0
+	yPos := game.AppState.Settings.Resolution.ToVec2().Y/2.0 - 250.0
 	panelWidth := float32(400.0)
-	panelHeight := float32(300.0)
+	panelHeight := float32(330.0)
+	progress := &game.World.Player.Progress
 	background := rl.NewRectangle(
 		xPos,
 		yPos,
@@ -57,100 +52,499 @@ func drawCharacterPanel() {
 	rl.DrawRectangleRounded(background, 0.05, 2, rendering.PanelBackground)
 	rl.DrawRectangleRoundedLines(background, 0.05, 2, 2.0, rendering.GoldAccent)
 
-	rendering.DrawSecondaryText(
+	game.Renderer.DrawSecondaryText(
 		rl.NewVector2(
 			xPos+panelWidth/2.0,
 			yPos+8.0,
 		),
 		24.0,
-		"CHARACTER",
+		fmt.Sprintf("CHARACTER  LEVEL %d", progress.Level),
 		rl.RayWhite,
 	)
 
-	rl.DrawTexture(*rendering.GetCharacterSprite(state.Player.State), int32(xPos)+10, int32(yPos)+8, rl.White)
+	rl.DrawTexture(*game.Renderer.GetCharacterSprite(game.World.Player.GetSprite()), int32(xPos)+10, int32(yPos)+8, rl.White)
 
-	rendering.DrawSecondaryText(
+	game.Renderer.DrawSecondaryText(
 		rl.NewVector2(
-			xPos+panelWidth/4.0,
+			xPos+panelWidth/5.5,
 			yPos+32.0,
 		),
 		24.0,
 		"STATS",
 		rl.RayWhite,
 	)
+	game.Renderer.DrawSecondaryText(
+		rl.NewVector2(
+			xPos+panelWidth*0.42,
+			yPos+32.0,
+		),
+		24.0,
+		"GEAR",
+		rl.RayWhite,
+	)
 
-	rendering.DrawSecondaryText(
+	game.Renderer.DrawSecondaryText(
 		rl.NewVector2(
-			xPos+panelWidth/5.5,
+			xPos+panelWidth*0.75,
+			yPos+32.0,
+		),
+		24.0,
+		fmt.Sprintf("health %.0f/%.0f", game.World.Player.Health, game.World.Player.MaxHealth()),
+		rl.RayWhite,
+	)
+
+	// Base stats on the left and with the equipment's bonuses next to them
+	base := &game.World.Player.Stats
+	effective := game.World.Player.EffectiveStats()
+	stats := []struct {
+		name      string
+		base      uint8
+		effective uint8
+	}{
+		{"strength", base.Strength, effective.Strength},
+		{"dexterity", base.Dexterity, effective.Dexterity},
+		{"vitality", base.Vitality, effective.Vitality},
+		{"movement", base.Movement, effective.Movement},
+		{"visibility", base.Visibility, effective.Visibility},
+	}
+	for i, stat := range stats {
+		rowY := yPos + 64.0 + float32(i)*22.0
+		if progress.Points > 0 && i == progress.Selected {
+			rl.DrawRectangleRoundedLines(rl.NewRectangle(xPos+8.0, rowY, panelWidth*0.5, 22.0), 0.3, 2, 1.0, rendering.GoldAccent)
+		}
+		game.Renderer.DrawSecondaryText(
+			rl.NewVector2(
+				xPos+panelWidth/5.5,
+				rowY,
+			),
+			24.0,
+			fmt.Sprintf("%v %v", stat.name, stat.base),
+			rl.RayWhite,
+		)
+
+		colour := rl.RayWhite
+		if stat.effective > stat.base {
+			colour = rendering.GoldAccent
+		} else if stat.effective < stat.base {
+			colour = rl.Red
+		}
+		game.Renderer.DrawSecondaryText(
+			rl.NewVector2(
+				xPos+panelWidth*0.42,
+				rowY,
+			),
+			24.0,
+			fmt.Sprint(stat.effective),
+			colour,
+		)
+	}
+
+	combatant := game.World.Player.Combatant()
+	damage := fmt.Sprintf("damage %.1f", sim.AttackDamage(&effective))
+	for _, dice := range combatant.Damage {
+		damage += " + " + dice.String()
+	}
+	game.Renderer.DrawSecondaryText(
+		rl.NewVector2(
+			xPos+panelWidth*0.75,
 			yPos+64.0,
 		),
 		24.0,
-		fmt.Sprintf("strength %v", state.Player.Stats.Strength),
+		damage,
 		rl.RayWhite,
 	)
-	rendering.DrawSecondaryText(
+	game.Renderer.DrawSecondaryText(
 		rl.NewVector2(
-			xPos+panelWidth/5.5,
+			xPos+panelWidth*0.75,
 			yPos+86.0,
 		),
 		24.0,
-		fmt.Sprintf("dexterity %v", state.Player.Stats.Dexterity),
+		fmt.Sprintf("armour %.0f  evasion %d", combatant.Armour, combatant.Evasion()),
 		rl.RayWhite,
 	)
-	rendering.DrawSecondaryText(
+
+	for i, slot := range []sim.ItemCategory{sim.ITEM_WEAPON, sim.ITEM_ARMOUR, sim.ITEM_TRINKET} {
+		name := "none"
+		if item := game.World.Player.Equipped(slot); item != nil {
+			name = item.Name
+		}
+		game.Renderer.DrawSecondaryText(
+			rl.NewVector2(
+				xPos+panelWidth*0.75,
+				yPos+130.0+float32(i)*22.0,
+			),
+			20.0,
+			fmt.Sprintf("%v: %v", slot, name),
+			rendering.SilverAccent,
+		)
+	}
+
+	// Experience towards the next level, and the points to spend once it is reached
+	previous := float32(sim.LevelThreshold(progress.Level))
+	fill := (float32(progress.Experience) - previous) / (float32(progress.NextLevel()) - previous)
+	bar := rl.NewRectangle(xPos+20.0, yPos+210.0, panelWidth-40.0, 16.0)
+	rl.DrawRectangleRec(bar, rendering.PanelBackground)
+	rl.DrawRectangleRec(rl.NewRectangle(bar.X, bar.Y, bar.Width*fill, bar.Height), rendering.GoldAccent)
+	rl.DrawRectangleLinesEx(bar, 1, rendering.SilverAccent)
+	game.Renderer.DrawSecondaryText(
 		rl.NewVector2(
-			xPos+panelWidth/5.5,
-			yPos+108.0,
+			xPos+panelWidth/2.0,
+			yPos+230.0,
+		),
+		20.0,
+		fmt.Sprintf("experience %d/%d", progress.Experience, progress.NextLevel()),
+		rl.RayWhite,
+	)
+
+	// Effects with the turns they have left
+	for i, effect := range game.World.Player.Effects {
+		columnX := xPos + 8.0 + float32(i)*78.0
+		drawEffectIcon(rl.NewRectangle(columnX, yPos+260.0, 10.0, 10.0), effect)
+		game.Renderer.DrawSecondaryText(
+			rl.NewVector2(
+				columnX+44.0,
+				yPos+256.0,
+			),
+			18.0,
+			effect.String(),
+			effectColour(effect.Kind),
+		)
+		game.Renderer.DrawSecondaryText(
+			rl.NewVector2(
+				columnX+44.0,
+				yPos+274.0,
+			),
+			18.0,
+			fmt.Sprintf("%d turns", effect.Turns),
+			rendering.SilverAccent,
+		)
+	}
+
+	if progress.Points > 0 {
+		game.Renderer.DrawSecondaryText(
+			rl.NewVector2(
+				xPos+panelWidth/2.0,
+				yPos+panelHeight-30.0,
+			),
+			20.0,
+			fmt.Sprintf("%d points to spend  N next stat  L raise", progress.Points),
+			rendering.GoldAccent,
+		)
+	}
+}
+
+func (game *GameState) drawInventoryPanel() {
+	RES := game.AppState.Settings.Resolution.ToVec2()
+	inventory := &game.World.Player.Inventory
+	panelWidth := float32(400.0)
+	panelHeight := float32(340.0)
+	xPos := RES.X - panelWidth - 25.0
+	yPos := RES.Y/2.0 - 250.0
+	background := rl.NewRectangle(
+		xPos,
+		yPos,
+		panelWidth,
+		panelHeight,
+	)
+
+	rl.DrawRectangleRounded(background, 0.05, 2, rendering.PanelBackground)
+	rl.DrawRectangleRoundedLines(background, 0.05, 2, 2.0, rendering.GoldAccent)
+
+	game.Renderer.DrawSecondaryText(
+		rl.NewVector2(
+			xPos+panelWidth/2.0,
+			yPos+8.0,
 		),
 		24.0,
-		fmt.Sprintf("vitality %v", state.Player.Stats.Vitality),
+		"INVENTORY",
 		rl.RayWhite,
 	)
-	rendering.DrawSecondaryText(
+
+	game.Renderer.DrawSecondaryText(
 		rl.NewVector2(
-			xPos+panelWidth/5.5,
-			yPos+130.0,
+			xPos+panelWidth/4.0,
+			yPos+32.0,
 		),
 		24.0,
-		fmt.Sprintf("movement %v", state.Player.Stats.Movement),
+		fmt.Sprintf("slots %d/%d", len(inventory.Items), sim.INVENTORY_SLOTS),
 		rl.RayWhite,
 	)
-	rendering.DrawSecondaryText(
+	game.Renderer.DrawSecondaryText(
 		rl.NewVector2(
-			xPos+panelWidth/5.5,
-			yPos+152.0,
+			xPos+panelWidth*0.75,
+			yPos+32.0,
 		),
 		24.0,
-		fmt.Sprintf("visibility %v", state.Player.Stats.Visibility),
+		fmt.Sprintf("weight %.1f/%.1f", inventory.Weight(), game.World.Player.CarryLimit()),
 		rl.RayWhite,
 	)
+
+	for i, item := range inventory.Items {
+		rowY := yPos + 64.0 + float32(i)*22.0
+		colour := rl.RayWhite
+		if i == inventory.Selected {
+			colour = rendering.GoldAccent
+			rl.DrawRectangleRoundedLines(rl.NewRectangle(xPos+8.0, rowY, panelWidth-16.0, 22.0), 0.3, 2, 1.0, rendering.GoldAccent)
+		}
+
+		category := string(item.Category)
+		if item.Equipped {
+			category = "equipped"
+		}
+
+		rl.DrawRectangleRounded(rl.NewRectangle(xPos+16.0, rowY+6.0, 10.0, 10.0), 0.3, 2, itemColour(item))
+		game.Renderer.DrawSecondaryText(
+			rl.NewVector2(
+				xPos+panelWidth/2.5,
+				rowY,
+			),
+			24.0,
+			item.Name,
+			colour,
+		)
+		game.Renderer.DrawSecondaryText(
+			rl.NewVector2(
+				xPos+panelWidth*0.8,
+				rowY,
+			),
+			24.0,
+			category,
+			colour,
+		)
+	}
+
+	game.Renderer.DrawSecondaryText(
+		rl.NewVector2(
+			xPos+panelWidth/2.0,
+			yPos+panelHeight-30.0,
+		),
+		20.0,
+		"G pick up  X drop  Q use  R equip  [ ] select",
+		rendering.SilverAccent,
+	)
 }
 
-func drawUI() {
-	RES := state.AppState.Settings.Resolution
-	if !state.Player.Turn.Done {
+func (game *GameState) drawUI() {
+	RES := game.AppState.Settings.Resolution
+	if !game.World.Player.Turn.Done {
 
-		for h := 0; h < int(state.Player.Turn.Actions); h++ {
-			rl.DrawTexture(*rendering.GetUISprite(rendering.SPRITE_ACTION_MARK), RES.X-100, int32(10+h*int(TILE_SIZE)+5), rl.White)
+		for h := 0; h < int(game.World.Player.Turn.Actions); h++ {
+			rl.DrawTexture(*game.Renderer.GetUISprite(rendering.SPRITE_ACTION_MARK), RES.X-100, int32(10+h*int(TILE_SIZE)+5), rl.White)
 		}
 
-		for m := 0; m < int(state.Player.Turn.Movement); m++ {
-			rl.DrawTexture(*rendering.GetUISprite(rendering.SPRITE_MOVEMENT_MARK), RES.X-60, int32(10+m*int(TILE_SIZE)+5), rl.White)
+		for m := 0; m < int(game.World.Player.Turn.Movement); m++ {
+			rl.DrawTexture(*game.Renderer.GetUISprite(rendering.SPRITE_MOVEMENT_MARK), RES.X-60, int32(10+m*int(TILE_SIZE)+5), rl.White)
 		}
 
-		if !(state.Player.Turn.Actions > 0) || !(state.Player.Turn.Movement > 0) {
-			rendering.DrawMainText(rl.NewVector2(float32(RES.X/2), float32(RES.Y)/1.1), 48.0, "ENTER TO END TURN", rl.RayWhite)
+		if !(game.World.Player.Turn.Actions > 0) || !(game.World.Player.Turn.Movement > 0) {
+			game.Renderer.DrawMainText(rl.NewVector2(float32(RES.X/2), float32(RES.Y)/1.1), 48.0, "ENTER TO END TURN", rl.RayWhite)
 		}
 	} else {
-		rendering.DrawMainText(rl.NewVector2(float32(RES.X/2), float32(RES.Y)/8.0), 48.0, "PROCESSING TURNS", rl.RayWhite)
+		game.Renderer.DrawMainText(rl.NewVector2(float32(RES.X/2), float32(RES.Y)/8.0), 48.0, "PROCESSING TURNS", rl.RayWhite)
+	}
+
+	game.Renderer.DrawSecondaryText(rl.NewVector2(float32(RES.X/2), 10.0), 24.0, fmt.Sprintf("FLOOR %d", game.World.Dungeon.Depth+1), rl.RayWhite)
+	if game.World.Player.Progress.Points > 0 && !game.UIState.CharacterPanelOpen && !game.World.Player.Turn.Done {
+		game.Renderer.DrawSecondaryText(rl.NewVector2(float32(RES.X/2), 90.0), 24.0, "LEVEL UP  C TO SPEND POINTS", rendering.GoldAccent)
+	}
+	game.drawHealthBar()
+	game.drawTurnOrderBar()
+	game.drawCombatLog()
+	if !game.UIState.CharacterPanelOpen {
+		game.drawAbilityBar()
+	}
+
+	if game.UIState.CharacterPanelOpen {
+		game.drawCharacterPanel()
+	}
+
+	if game.UIState.InventoryPanelOpen {
+		game.drawInventoryPanel()
+	}
+
+	if game.UIState.DebugDisplay.Enabled {
+		game.drawDebugSettings()
+		game.drawDebugInfo()
+	}
+}
+
+func (game *GameState) drawHealthBar() {
+	RES := game.AppState.Settings.Resolution
+	const width = 200.0
+	const height = 20.0
+
+	fill := game.World.Player.Health / game.World.Player.MaxHealth()
+	if fill < 0.0 {
+		fill = 0.0
+	}
+
+	background := rl.NewRectangle(20.0, float32(RES.Y)-height-20.0, width, height)
+	rl.DrawRectangleRec(background, rendering.PanelBackground)
+	rl.DrawRectangleRec(rl.NewRectangle(background.X, background.Y, width*fill, height), rl.Maroon)
+	rl.DrawRectangleLinesEx(background, 2, rendering.GoldAccent)
+
+	game.Renderer.DrawSecondaryText(
+		rl.NewVector2(background.X+width/2.0, background.Y),
+		20.0,
+		fmt.Sprintf("%.0f/%.0f", game.World.Player.Health, game.World.Player.MaxHealth()),
+		rl.RayWhite,
+	)
+}
+
+// Lines shown in the combat log, the rest can be found in the event log
+const COMBAT_LOG_LINES = 6
+
+func isCombatEvent(kind int) bool {
+	switch kind {
+	case sim.EVENT_ATTACKED, sim.EVENT_MISSED, sim.EVENT_KILLED, sim.EVENT_PLAYER_HIT, sim.EVENT_DODGED, sim.EVENT_PLAYER_DIED,
+		sim.EVENT_AFFECTED, sim.EVENT_EFFECT_DAMAGE, sim.EVENT_EFFECT_ENDED, sim.EVENT_USED_ABILITY:
+		return true
+	}
+	return false
+}
+
+// The latest attacks with their rolls, newest at the bottom just above the health bar
+func (game *GameState) drawCombatLog() {
+	RES := game.AppState.Settings.Resolution
+	var lines []sim.Event
+	entries := game.World.Events.Entries
+	for i := len(entries) - 1; i >= 0 && len(lines) < COMBAT_LOG_LINES; i-- {
+		if isCombatEvent(entries[i].Kind) {
+			lines = append(lines, entries[i])
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	const width = 640.0
+	const lineHeight = 20.0
+	height := float32(len(lines))*lineHeight + 8.0
+	background := rl.NewRectangle(20.0, float32(RES.Y)-height-50.0, width, height)
+	rl.DrawRectangleRounded(background, 0.05, 2, rendering.PanelBackground)
+	rl.DrawRectangleRoundedLines(background, 0.05, 2, 2.0, rendering.GoldAccent)
+
+	for i, event := range lines {
+		colour := rl.RayWhite
+		switch event.Kind {
+		case sim.EVENT_PLAYER_HIT, sim.EVENT_PLAYER_DIED:
+			colour = rl.Red
+		case sim.EVENT_MISSED, sim.EVENT_DODGED:
+			colour = rendering.SilverAccent
+		case sim.EVENT_KILLED:
+			colour = rendering.GoldAccent
+		}
+		game.Renderer.DrawSecondaryText(
+			rl.NewVector2(
+				background.X+width/2.0,
+				background.Y+height-4.0-float32(i+1)*lineHeight,
+			),
+			18.0,
+			event.Message,
+			colour,
+		)
+	}
+}
+
+// Upcoming turns of the player and the enemies they can see, left to right
+func (game *GameState) drawTurnOrderBar() {
+	RES := game.AppState.Settings.Resolution
+	const padding = 4
+
+	var characters []sim.Character
+	for _, character := range sim.TurnOrder(game.World.Player, game.World.Enemies, sim.TURN_ORDER_LENGTH*2) {
+		if enemy, ok := character.(*sim.Enemy); ok && !enemy.VisibleToPlayer(game.World) {
+			continue
+		}
+		characters = append(characters, character)
+		if len(characters) == sim.TURN_ORDER_LENGTH {
+			break
+		}
+	}
+
+	size := TILE_SIZE + padding*2
+	xPos := RES.X/2 - int32(len(characters))*size/2
+	for i, character := range characters {
+		bounds := rl.NewRectangle(float32(xPos+int32(i)*size), 40.0, float32(size-padding), float32(size-padding))
+		border := rendering.SilverAccent
+		if _, ok := character.(*sim.Player); ok {
+			border = rendering.GoldAccent
+		}
+
+		rl.DrawRectangleRec(bounds, rendering.PanelBackground)
+		rl.DrawRectangleLinesEx(bounds, 2, border)
+		rl.DrawTexture(*game.Renderer.GetCharacterSprite(character.GetSprite()), int32(bounds.X)+padding/2, int32(bounds.Y)+padding/2, rl.White)
+	}
+}
+
+// Drawn by the main loop once the player has died, the game state is kept around until then
+func (game *GameState) DrawGameOver() {
+	appState := game.AppState
+	RES := appState.Settings.Resolution
+
+	game.Renderer.DrawMainText(rl.NewVector2(float32(RES.X/2), float32(RES.Y/6)), 96.0, "YOU DIED", rl.Maroon)
+
+	game.Renderer.DrawSecondaryText(
+		rl.NewVector2(float32(RES.X)/2.0, float32(RES.Y)/2.0-40.0),
+		24.0,
+		fmt.Sprintf("reached floor %d with %d kills", game.World.Dungeon.Depth+1, game.World.Kills),
+		rl.RayWhite,
+	)
+	game.Renderer.DrawSecondaryText(
+		rl.NewVector2(float32(RES.X)/2.0, float32(RES.Y)/2.0-10.0),
+		24.0,
+		fmt.Sprintf("seed %d", appState.ActiveSeed),
+		rl.RayWhite,
+	)
+
+	if game.Renderer.DrawButton(rl.NewVector2(float32(RES.X)/2.0, float32(RES.Y)/2.0+50.0), "MAIN MENU") {
+		appState.View = utils.MAIN_MENU
 	}
+}
 
-	if state.UIState.CharacterPanelOpen {
-		drawCharacterPanel()
+// The hotbar in the top left corner, the selected ability in gold with its costs underneath
+func (game *GameState) drawAbilityBar() {
+	abilities := game.World.Data.Abilities
+	if len(abilities) == 0 {
+		return
 	}
 
-	if state.UIState.DebugDisplay.Enabled {
-		drawDebugSettings()
-		drawDebugInfo()
+	bar := &game.World.Player.Abilities
+	const width = 220.0
+	const lineHeight = 22.0
+	height := float32(len(abilities))*lineHeight + 52.0
+	background := rl.NewRectangle(20.0, 10.0, width, height)
+	rl.DrawRectangleRounded(background, 0.05, 2, rendering.PanelBackground)
+	rl.DrawRectangleRoundedLines(background, 0.05, 2, 2.0, rendering.GoldAccent)
+
+	for i, ability := range abilities {
+		rowY := background.Y + 6.0 + float32(i)*lineHeight
+		colour := rl.RayWhite
+		if i == bar.Selected {
+			colour = rendering.GoldAccent
+			rl.DrawRectangleRoundedLines(rl.NewRectangle(background.X+6.0, rowY, width-12.0, lineHeight), 0.3, 2, 1.0, rendering.GoldAccent)
+		}
+		status := "ready"
+		if rounds := bar.Cooldowns[ability.Name]; rounds > 0 {
+			colour = rl.Gray
+			status = fmt.Sprintf("%d rounds", rounds)
+		}
+		game.Renderer.DrawSecondaryText(rl.NewVector2(background.X+width*0.35, rowY), 20.0, ability.Name, colour)
+		game.Renderer.DrawSecondaryText(rl.NewVector2(background.X+width*0.8, rowY), 18.0, status, rendering.SilverAccent)
+	}
+
+	if ability := bar.SelectedAbility(abilities); ability != nil {
+		game.Renderer.DrawSecondaryText(
+			rl.NewVector2(background.X+width/2.0, background.Y+height-46.0),
+			18.0,
+			ability.Costs(),
+			rendering.SilverAccent,
+		)
 	}
+	game.Renderer.DrawSecondaryText(
+		rl.NewVector2(background.X+width/2.0, background.Y+height-26.0),
+		18.0,
+		"T next  SPACE aim  F use",
+		rendering.SilverAccent,
+	)
 }
@@ -3,16 +3,25 @@ module kiikkupaskaa
 go 1.17
 
 require (
+	fov v0.0.0 //indirect
 	game v0.0.0 //indirect
 	github.com/gen2brain/raylib-go/raygui v0.0.0-20210906160657-aabc97d1c242 // indirect
 	github.com/gen2brain/raylib-go/raylib v0.0.0-20210905161606-6acb55e3e6d3 // indirect
 	github.com/ojrac/opensimplex-go v1.0.2 // indirect
+	pathfinding v0.0.0 //indirect
 	rendering v0.0.0 //indirect
+	sim v0.0.0 //indirect
 	utils v0.0.0 //indirect
 )
 
+replace fov v0.0.0 => ./fov
+
 replace game v0.0.0 => ./game
 
+replace pathfinding v0.0.0 => ./pathfinding
+
 replace rendering v0.0.0 => ./rendering
 
+replace sim v0.0.0 => ./sim
+
 replace utils v0.0.0 => ./utils
@@ -9,6 +9,7 @@ import (
 
 	"game"
 	"rendering"
+	"sim"
 	"utils"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
@@ -16,6 +17,10 @@ import (
 
 var state utils.State
 var debugMode = false
+var headless = false
+var headlessRounds = 0
+var replay *sim.Replay
+var data *sim.Data
 
 func init() {
 	runtime.LockOSThread()
@@ -27,10 +32,64 @@ func init() {
 	heightFlag := flag.Int("h", 600, "Define the window height")
 	musicFlag := flag.Bool("music", true, "Enable or disable music")
 	debugFlag := flag.Bool("debug", false, "Enable debug mode")
+	seedFlag := flag.Int64("seed", 0, "Seed used for level generation, 0 picks a random seed")
+	levelFlag := flag.String("level", "", "Load a level from a .map file instead of generating one")
+	generatorFlag := flag.String("generator", sim.DEFAULT_GENERATOR, fmt.Sprintf("Map generator to use, one of %v", sim.GeneratorNames()))
+	recordFlag := flag.String("record", "", "Record the inputs of the run to a replay file")
+	replayFlag := flag.String("replay", "", "Play back a replay file recorded with -record")
+	headlessFlag := flag.Bool("headless", false, "Run without a window: play back the -replay and print the final state hash, or simulate -rounds rounds and print stats")
+	roundsFlag := flag.Int("rounds", 100, "Rounds to simulate with -headless when there is no -replay")
+	mapFlags := map[string]*string{
+		"size":               flag.String("map-size", "", "Size of generated maps as WIDTHxHEIGHT"),
+		"noise-scale":        flag.String("noise-scale", "", "Noise sampling step per tile for cave maps"),
+		"obstacle-threshold": flag.String("obstacle-threshold", "", "Noise magnitude above which cave tiles become rubble"),
+		"wall-threshold":     flag.String("wall-threshold", "", "Noise value above which cave tiles become walls"),
+		"floor-threshold":    flag.String("floor-threshold", "", "Noise value above which cave tiles become floor, below it moss walls"),
+		"spawn-rate":         flag.String("spawn-rate", "", "Chance of a floor tile becoming a spawn point"),
+	}
 
 	flag.Parse()
 
-	log.Printf("Running with flags: -w %d -h %d -music=%v", *widthFlag, *heightFlag, *musicFlag)
+	log.Printf("Running with flags: -w %d -h %d -music=%v -seed %d -level %q -generator %v -record %q -replay %q -headless=%v -rounds %d", *widthFlag, *heightFlag, *musicFlag, *seedFlag, *levelFlag, *generatorFlag, *recordFlag, *replayFlag, *headlessFlag, *roundsFlag)
+
+	// Level files name the archetypes of their enemies, which name the items they carry
+	var err error
+	if data, err = sim.LoadData(); err != nil {
+		log.Fatal(err)
+	}
+
+	if _, ok := sim.GetGenerator(*generatorFlag); !ok {
+		log.Fatalf("Unknown generator %q, expected one of %v", *generatorFlag, sim.GeneratorNames())
+	}
+
+	mapOverrides := make(map[string]string)
+	mapConfig := sim.DefaultGeneratorConfig()
+	for key, value := range mapFlags {
+		if *value != "" {
+			if err := mapConfig.Set(key, *value); err != nil {
+				log.Fatal(err)
+			}
+			mapOverrides[key] = *value
+		}
+	}
+	if err := mapConfig.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	if *levelFlag != "" {
+		level, err := sim.LoadLevelFile(*levelFlag, data)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if level.TileSet != "" && !rendering.TileSetExists(level.TileSet) {
+			log.Fatalf("%v: unknown tileset %q", level.Path, level.TileSet)
+		}
+	}
+
+	seedInput := ""
+	if *seedFlag != 0 {
+		seedInput = fmt.Sprint(*seedFlag)
+	}
 
 	debugMode = *debugFlag
 	state = utils.State{
@@ -40,13 +99,41 @@ func init() {
 			PanelVisible: false,
 			Resolution:   utils.IVector2{X: int32(*widthFlag), Y: int32(*heightFlag)},
 			Music:        *musicFlag,
+			Seed:         *seedFlag,
+			SeedInput:    seedInput,
+			LevelPath:    *levelFlag,
+			Generator:    *generatorFlag,
+			MapOverrides: mapOverrides,
+			RecordPath:   *recordFlag,
 		},
 		RenderAssets: nil,
 	}
+
+	if *replayFlag != "" {
+		var err error
+		if replay, err = sim.LoadReplay(*replayFlag); err != nil {
+			log.Fatal(err)
+		}
+		game.ApplyReplay(replay, &state.Settings)
+		state.View = utils.IN_GAME
+	}
+	headless = *headlessFlag
+	headlessRounds = *roundsFlag
 }
 
 func main() {
 	utils.InitUtils(&state, debugMode)
+	sim.DebugMode = debugMode
+
+	if headless {
+		if replay != nil {
+			fmt.Printf("%016x\n", sim.RunReplay(replay, data))
+		} else {
+			fmt.Print(sim.Simulate(game.ConfigFromSettings(&state.Settings, data), headlessRounds))
+		}
+		return
+	}
+
 	rl.InitWindow(state.Settings.Resolution.X, state.Settings.Resolution.Y, "Kiikkupaskaa")
 	rl.SetTargetFPS(int32(rl.GetMonitorRefreshRate(rl.GetCurrentMonitor())))
 	rl.SetExitKey(rl.KeyF4)
@@ -54,10 +141,11 @@ func main() {
 	icon := rl.LoadImage("assets/fav.png")
 	rl.SetWindowIcon(*icon)
 
-	state.RenderAssets = rendering.LoadAssets(&state)
+	renderer := rendering.LoadAssets(&state, data.Archetypes)
 	rl.InitAudioDevice()
 
 	var gameState *game.GameState
+	var loader *game.Loader
 
 	var menuMusic rl.Music
 	menuMusic = rl.LoadMusicStream(utils.GetAssetPath(utils.MUSIC, "main_menu01.mp3"))
@@ -106,8 +194,13 @@ func main() {
 
 			rl.EndDrawing()
 
-			if !state.RenderAssets.TestTextures.Loaded {
-				state.RenderAssets.TestTextures = rendering.BuildTileSet("wall_stone_tile")
+			if loader != nil {
+				if loaded, ok := loader.Done(); ok {
+					gameState = loaded
+					loader = nil
+				}
+			} else if !renderer.Assets.TestTextures.Loaded {
+				renderer.Assets.TestTextures = rendering.BuildTileSet(rendering.DEFAULT_TILESET)
 				state.Loading = false
 			}
 		} else {
@@ -126,7 +219,7 @@ func main() {
 
 				rl.ClearBackground(rl.Black)
 
-				rendering.DrawMenuButtons(state.View, &exitWindow)
+				renderer.DrawMenuButtons(state.View, &exitWindow)
 
 				rl.EndDrawing()
 
@@ -146,7 +239,7 @@ func main() {
 				rl.BeginDrawing()
 
 				rl.ClearBackground(rl.Black)
-				rendering.DrawMenuButtons(state.View, &exitWindow)
+				renderer.DrawMenuButtons(state.View, &exitWindow)
 
 				rl.EndDrawing()
 
@@ -155,12 +248,32 @@ func main() {
 			//*
 			//*
 			case utils.IN_GAME:
-				game.GameUpdate(&state, &gameState)
+				if gameState == nil {
+					loader = game.StartLoading(&state, renderer, data)
+				} else {
+					gameState.Update()
+				}
+
+			//*
+			//*	Game over screen
+			//*
+			//*
+			case utils.GAME_OVER:
+				if rl.IsKeyPressed(rl.KeyEnter) {
+					state.View = utils.MAIN_MENU
+				}
+
+				rl.BeginDrawing()
+
+				rl.ClearBackground(rl.Black)
+				gameState.DrawGameOver()
+
+				rl.EndDrawing()
 			}
 		}
 	}
 
-	rendering.Cleanup()
+	renderer.Cleanup()
 
 	rl.CloseAudioDevice()
 	rl.CloseWindow()
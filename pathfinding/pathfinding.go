@@ -0,0 +1,213 @@
+package pathfinding
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Point is a tile coordinate, not a pixel position
+type Point struct {
+	X int
+	Y int
+}
+
+// Rules for moving between diagonal neighbours
+const (
+	DIAGONAL_NEVER      = iota
+	DIAGONAL_NO_CORNERS = iota // Only when both orthogonal neighbours are passable
+	DIAGONAL_ONE_CORNER = iota // Only when at least one orthogonal neighbour is passable
+	DIAGONAL_ALWAYS     = iota
+)
+
+const DIAGONAL_COST_MULT = math.Sqrt2
+const DEFAULT_MAX_SEARCHED = 10000
+const BLOCKED = -1.0
+const MIN_COST = 1.0
+
+type Options struct {
+	Diagonals int
+	// Cost of stepping onto the tile, BLOCKED (or anything below zero) for impassable tiles.
+	// Costs are clamped to MIN_COST so the distance estimate never overshoots.
+	Cost func(x int, y int) float64
+	// Stop searching after this many tiles, 0 uses DEFAULT_MAX_SEARCHED
+	MaxSearched int
+	// When the goal can't be reached return the path to the tile closest to it instead
+	Closest bool
+}
+
+var orthogonal = [4]Point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+var diagonal = [4]Point{{1, -1}, {1, 1}, {-1, 1}, {-1, -1}}
+
+// FindPath searches for the cheapest path with A*.
+// The returned path leaves out from and ends at to, ok is false if there was no path.
+// The goal is always considered passable, so characters can path to an occupied tile.
+func FindPath(from Point, to Point, options Options) ([]Point, bool) {
+	if from == to {
+		return nil, true
+	}
+
+	maxSearched := options.MaxSearched
+	if maxSearched <= 0 {
+		maxSearched = DEFAULT_MAX_SEARCHED
+	}
+
+	cost := func(p Point) float64 {
+		if p == to {
+			return MIN_COST
+		}
+		c := options.Cost(p.X, p.Y)
+		if c < 0 {
+			return BLOCKED
+		}
+		return math.Max(c, MIN_COST)
+	}
+
+	open := &nodeQueue{}
+	nodes := map[Point]*node{from: {pos: from, estimate: estimate(from, to, options.Diagonals)}}
+	heap.Push(open, nodes[from])
+	closest := nodes[from]
+	searched := 0
+
+	for open.Len() > 0 && searched < maxSearched {
+		current := heap.Pop(open).(*node)
+		current.closed = true
+		searched++
+
+		if current.pos == to {
+			return current.path(), true
+		}
+		if current.estimate-current.cost < closest.estimate-closest.cost {
+			closest = current
+		}
+
+		for _, next := range neighbours(current.pos, options.Diagonals, cost) {
+			stepCost := cost(next)
+			if next.X != current.pos.X && next.Y != current.pos.Y {
+				stepCost *= DIAGONAL_COST_MULT
+			}
+			total := current.cost + stepCost
+
+			n, seen := nodes[next]
+			if seen && (n.closed || n.cost <= total) {
+				continue
+			}
+			if !seen {
+				n = &node{pos: next}
+				nodes[next] = n
+			}
+			n.parent = current
+			n.cost = total
+			n.estimate = total + estimate(next, to, options.Diagonals)
+			if seen {
+				heap.Fix(open, n.index)
+			} else {
+				heap.Push(open, n)
+			}
+		}
+	}
+
+	if options.Closest && closest.pos != from {
+		return closest.path(), false
+	}
+	return nil, false
+}
+
+func neighbours(pos Point, diagonals int, cost func(p Point) float64) []Point {
+	var result []Point
+	var passable [4]bool
+	for i, d := range orthogonal {
+		p := Point{X: pos.X + d.X, Y: pos.Y + d.Y}
+		if cost(p) >= 0 {
+			passable[i] = true
+			result = append(result, p)
+		}
+	}
+
+	if diagonals == DIAGONAL_NEVER {
+		return result
+	}
+
+	for i, d := range diagonal {
+		// The orthogonal neighbours on either side of the diagonal
+		a := passable[i]
+		b := passable[(i+1)%4]
+		switch diagonals {
+		case DIAGONAL_NO_CORNERS:
+			if !a || !b {
+				continue
+			}
+		case DIAGONAL_ONE_CORNER:
+			if !a && !b {
+				continue
+			}
+		}
+
+		p := Point{X: pos.X + d.X, Y: pos.Y + d.Y}
+		if cost(p) >= 0 {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// Manhattan distance for 4-way movement, octile distance when diagonals are allowed
+func estimate(from Point, to Point, diagonals int) float64 {
+	dx := math.Abs(float64(to.X - from.X))
+	dy := math.Abs(float64(to.Y - from.Y))
+	if diagonals == DIAGONAL_NEVER {
+		return (dx + dy) * MIN_COST
+	}
+	return (math.Max(dx, dy) + (DIAGONAL_COST_MULT-1)*math.Min(dx, dy)) * MIN_COST
+}
+
+type node struct {
+	pos      Point
+	parent   *node
+	cost     float64
+	estimate float64
+	closed   bool
+	index    int
+}
+
+func (n *node) path() []Point {
+	var path []Point
+	for current := n; current.parent != nil; current = current.parent {
+		path = append(path, current.pos)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+type nodeQueue []*node
+
+func (q nodeQueue) Len() int { return len(q) }
+
+func (q nodeQueue) Less(i int, j int) bool {
+	if q[i].estimate == q[j].estimate {
+		// Prefer the node closer to the goal on ties
+		return q[i].cost > q[j].cost
+	}
+	return q[i].estimate < q[j].estimate
+}
+
+func (q nodeQueue) Swap(i int, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *nodeQueue) Push(x interface{}) {
+	n := x.(*node)
+	n.index = len(*q)
+	*q = append(*q, n)
+}
+
+func (q *nodeQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	n.index = -1
+	*q = old[:len(old)-1]
+	return n
+}
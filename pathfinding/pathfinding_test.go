@@ -0,0 +1,246 @@
+package pathfinding
+
+import (
+	"strings"
+	"testing"
+)
+
+// Fixture maps: '#' is a wall, '.' floor, ',' rubble that costs 3 to cross,
+// 's' the start and 'g' the goal. Expected maps mark the path with '*'.
+type fixture struct {
+	tiles []string
+	start Point
+	goal  Point
+}
+
+func parseFixture(t *testing.T, rows string) fixture {
+	f := fixture{tiles: strings.Split(strings.Trim(rows, "\n"), "\n")}
+	for y, row := range f.tiles {
+		if x := strings.IndexByte(row, 's'); x >= 0 {
+			f.start = Point{X: x, Y: y}
+		}
+		if x := strings.IndexByte(row, 'g'); x >= 0 {
+			f.goal = Point{X: x, Y: y}
+		}
+	}
+	return f
+}
+
+func (f fixture) cost(x int, y int) float64 {
+	if y < 0 || y >= len(f.tiles) || x < 0 || x >= len(f.tiles[y]) {
+		return BLOCKED
+	}
+	switch f.tiles[y][x] {
+	case '#':
+		return BLOCKED
+	case ',':
+		return 3
+	}
+	return 1
+}
+
+func render(f fixture, path []Point) string {
+	rows := make([][]byte, len(f.tiles))
+	for y, row := range f.tiles {
+		rows[y] = []byte(row)
+	}
+	for _, p := range path {
+		rows[p.Y][p.X] = '*'
+	}
+
+	var lines []string
+	for _, row := range rows {
+		lines = append(lines, string(row))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func checkFixture(t *testing.T, name string, rows string, diagonals int, expected string) {
+	t.Helper()
+	f := parseFixture(t, rows)
+	path, ok := FindPath(f.start, f.goal, Options{Diagonals: diagonals, Cost: f.cost})
+	if !ok {
+		t.Fatalf("%v: no path found", name)
+	}
+	if path[len(path)-1] != f.goal {
+		t.Errorf("%v: path ends at %v instead of the goal %v", name, path[len(path)-1], f.goal)
+	}
+
+	// Leave the goal out so the expected maps stay readable
+	got := render(f, path[:len(path)-1])
+	want := strings.Trim(expected, "\n")
+	if got != want {
+		t.Errorf("%v:\ngot\n%v\nwant\n%v", name, got, want)
+	}
+}
+
+func TestStraightLine(t *testing.T) {
+	checkFixture(t, "straight line", `
+s....g
+`, DIAGONAL_NEVER, `
+s****g
+`)
+}
+
+func TestAroundWall(t *testing.T) {
+	checkFixture(t, "around wall", `
+######
+#....#
+#s##g#
+#.####
+######
+`, DIAGONAL_NEVER, `
+######
+#****#
+#s##g#
+#.####
+######
+`)
+}
+
+func TestWindingCorridor(t *testing.T) {
+	checkFixture(t, "winding corridor", `
+#########
+#s#...#g#
+#.#.#.#.#
+#...#...#
+#########
+`, DIAGONAL_NEVER, `
+#########
+#s#***#g#
+#*#*#*#*#
+#***#***#
+#########
+`)
+}
+
+func TestAvoidsExpensiveTiles(t *testing.T) {
+	checkFixture(t, "rubble detour", `
+#######
+#s,,,g#
+#.###.#
+#.....#
+#######
+`, DIAGONAL_NEVER, `
+#######
+#s,,,g#
+#*###*#
+#*****#
+#######
+`)
+
+	// The detour is longer than crossing the rubble here
+	checkFixture(t, "rubble shortcut", `
+#######
+#s,..g#
+#.###.#
+#.....#
+#######
+`, DIAGONAL_NEVER, `
+#######
+#s***g#
+#.###.#
+#.....#
+#######
+`)
+}
+
+func TestDiagonals(t *testing.T) {
+	checkFixture(t, "open room", `
+#####
+#s..#
+#...#
+#..g#
+#####
+`, DIAGONAL_ALWAYS, `
+#####
+#s..#
+#.*.#
+#..g#
+#####
+`)
+
+	oneCorner := parseFixture(t, `
+####
+#s##
+#.g#
+####
+`)
+	bothCorners := parseFixture(t, `
+####
+#s##
+##g#
+####
+`)
+	cases := []struct {
+		name      string
+		f         fixture
+		diagonals int
+		length    int
+	}{
+		{"never", oneCorner, DIAGONAL_NEVER, 2},
+		{"no corners past a wall", oneCorner, DIAGONAL_NO_CORNERS, 2},
+		{"one corner past a wall", oneCorner, DIAGONAL_ONE_CORNER, 1},
+		{"one corner between walls", bothCorners, DIAGONAL_ONE_CORNER, 0},
+		{"always between walls", bothCorners, DIAGONAL_ALWAYS, 1},
+	}
+	for _, c := range cases {
+		path, ok := FindPath(c.f.start, c.f.goal, Options{Diagonals: c.diagonals, Cost: c.f.cost})
+		if c.length == 0 {
+			if ok {
+				t.Errorf("%v: expected no path, got %v", c.name, path)
+			}
+		} else if !ok || len(path) != c.length {
+			t.Errorf("%v: expected a path of %d steps, got %v", c.name, c.length, path)
+		}
+	}
+}
+
+func TestBlocked(t *testing.T) {
+	f := parseFixture(t, `
+#######
+#s.#.g#
+#######
+`)
+	if path, ok := FindPath(f.start, f.goal, Options{Cost: f.cost}); ok || path != nil {
+		t.Errorf("found a path through a wall: %v", path)
+	}
+
+	path, ok := FindPath(f.start, f.goal, Options{Cost: f.cost, Closest: true})
+	if ok {
+		t.Error("closest path reported as reaching the goal")
+	}
+	if len(path) != 1 || path[0] != (Point{X: 2, Y: 1}) {
+		t.Errorf("closest path should end next to the wall, got %v", path)
+	}
+}
+
+func TestGoalAlwaysPassable(t *testing.T) {
+	f := parseFixture(t, `
+####
+#sg#
+####
+`)
+	occupied := func(x int, y int) float64 {
+		if x == f.goal.X && y == f.goal.Y {
+			return BLOCKED
+		}
+		return f.cost(x, y)
+	}
+	if path, ok := FindPath(f.start, f.goal, Options{Cost: occupied}); !ok || len(path) != 1 {
+		t.Errorf("expected a one step path onto the occupied goal, got %v", path)
+	}
+}
+
+func TestStartIsGoal(t *testing.T) {
+	if path, ok := FindPath(Point{X: 1, Y: 1}, Point{X: 1, Y: 1}, Options{Cost: func(int, int) float64 { return 1 }}); !ok || len(path) != 0 {
+		t.Errorf("expected an empty path, got %v", path)
+	}
+}
+
+func TestMaxSearched(t *testing.T) {
+	open := func(int, int) float64 { return 1 }
+	if _, ok := FindPath(Point{}, Point{X: 500, Y: 500}, Options{Cost: open, MaxSearched: 100}); ok {
+		t.Error("search should have given up")
+	}
+}
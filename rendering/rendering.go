@@ -3,6 +3,8 @@ package rendering
 import (
 	"fmt"
 	"log"
+	"os"
+	"sim"
 	"time"
 	"utils"
 
@@ -10,69 +12,98 @@ import (
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
-var Assets utils.RenderingAssets
-var appState *utils.State
+const DEFAULT_TILESET = "wall_stone_tile"
 
-const (
-	TILE_FLOOR_STONE    = iota
-	TILE_WALL_STONE     = iota
-	TILE_WALL_MOSS      = iota
-	TILE_FLOOR_SPAWN    = iota
-	TILE_FLOOR_OBS      = iota
-	TILE_FLOOR_STONE_BL = iota
-)
+// Renderer draws with the assets it loaded, the menus change the app state it was loaded for
+type Renderer struct {
+	Assets   *utils.RenderingAssets
+	AppState *utils.State
+}
 
-func LoadAssets(state *utils.State) *utils.RenderingAssets {
+// Also makes the assets the app state's RenderAssets, the sprites of the archetypes are loaded up front
+func LoadAssets(state *utils.State, archetypes []*sim.Archetype) *Renderer {
 	missingImg := rl.GenImageColor(32, 32, rl.Pink)
 	missingTexture := rl.LoadTextureFromImage(missingImg)
 	rl.UnloadImage(missingImg)
 
 	main, sec := loadFonts()
-	Assets = utils.RenderingAssets{
+	assets := utils.RenderingAssets{
 		TileTextures:     loadTileTextures(),
-		CharacterSprites: loadCharacterSprites(),
+		CharacterSprites: loadCharacterSprites(archetypes),
 		UISprites:        loadUISprites(),
 		MissingTexture:   &missingTexture,
 		MainFont:         main,
 		SecondaryFont:    sec,
+		TileSets:         make(map[string]*utils.TileSet),
 	}
 	loadGUIStylesheet()
-	appState = state
-	return &Assets
+	state.RenderAssets = &assets
+	return &Renderer{Assets: &assets, AppState: state}
 }
 
-func Cleanup() {
+func (renderer *Renderer) Cleanup() {
 	rl.SetTraceLog(rl.LogNone)
-	for _, t := range Assets.TileTextures {
+	for _, t := range renderer.Assets.TileTextures {
 		rl.UnloadTexture(t)
 	}
-	for _, t := range Assets.CharacterSprites {
+	for _, t := range renderer.Assets.CharacterSprites {
 		rl.UnloadTexture(t)
 	}
-	for _, t := range Assets.UISprites {
+	for _, t := range renderer.Assets.UISprites {
 		rl.UnloadTexture(t)
 	}
-	for _, t := range Assets.TestTextures.Textures {
+	for _, t := range renderer.Assets.TestTextures.Textures {
 		rl.UnloadTexture(t)
 	}
+	for _, tileset := range renderer.Assets.TileSets {
+		for _, t := range tileset.Textures {
+			rl.UnloadTexture(t)
+		}
+	}
 	rl.SetTraceLog(rl.LogInfo)
 
-	rl.UnloadFont(Assets.MainFont)
-	rl.UnloadFont(Assets.SecondaryFont)
+	rl.UnloadFont(renderer.Assets.MainFont)
+	rl.UnloadFont(renderer.Assets.SecondaryFont)
 }
 
 func loadTileTextures() []rl.Texture2D {
-	texturelist := make([]rl.Texture2D, 7)
-	texturelist[TILE_FLOOR_STONE] = rl.LoadTexture(utils.GetAssetPath(utils.TEXTURE, "floor_stone_tile.png"))
-	texturelist[TILE_WALL_STONE] = rl.LoadTexture(utils.GetAssetPath(utils.TEXTURE, "wall_stone_tile.png"))
-	texturelist[TILE_WALL_MOSS] = rl.LoadTexture(utils.GetAssetPath(utils.TEXTURE, "wall_moss_tile.png"))
-	texturelist[TILE_FLOOR_SPAWN] = rl.LoadTexture(utils.GetAssetPath(utils.TEXTURE, "floor_spawn_tile.png"))
-	texturelist[TILE_FLOOR_OBS] = rl.LoadTexture(utils.GetAssetPath(utils.TEXTURE, "floor_obs_tile.png"))
-	texturelist[TILE_FLOOR_STONE_BL] = rl.LoadTexture(utils.GetAssetPath(utils.TEXTURE, "floor_stone_tile_bl.png"))
+	texturelist := make([]rl.Texture2D, 8)
+	texturelist[sim.TILE_FLOOR_STONE] = rl.LoadTexture(utils.GetAssetPath(utils.TEXTURE, "floor_stone_tile.png"))
+	texturelist[sim.TILE_WALL_STONE] = rl.LoadTexture(utils.GetAssetPath(utils.TEXTURE, "wall_stone_tile.png"))
+	texturelist[sim.TILE_WALL_MOSS] = rl.LoadTexture(utils.GetAssetPath(utils.TEXTURE, "wall_moss_tile.png"))
+	texturelist[sim.TILE_FLOOR_SPAWN] = rl.LoadTexture(utils.GetAssetPath(utils.TEXTURE, "floor_spawn_tile.png"))
+	texturelist[sim.TILE_FLOOR_OBS] = rl.LoadTexture(utils.GetAssetPath(utils.TEXTURE, "floor_obs_tile.png"))
+	texturelist[sim.TILE_FLOOR_STONE_BL] = rl.LoadTexture(utils.GetAssetPath(utils.TEXTURE, "floor_stone_tile_bl.png"))
+	texturelist[sim.TILE_STAIRS_UP] = rl.LoadTexture(utils.GetAssetPath(utils.TEXTURE, "stairs_up_tile.png"))
+	texturelist[sim.TILE_STAIRS_DOWN] = rl.LoadTexture(utils.GetAssetPath(utils.TEXTURE, "stairs_down_tile.png"))
 
 	return texturelist
 }
 
+func TileSetExists(name string) bool {
+	for _, suffix := range []string{"", "_vert", "_hor", "_cor", "_incor"} {
+		if _, err := os.Stat(utils.GetAssetPath(utils.TEXTURE, fmt.Sprintf("%v%v.png", name, suffix))); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Tilesets other than the default one are built the first time they are drawn
+func (renderer *Renderer) GetTileSet(name string) *utils.TileSet {
+	if name == "" || name == DEFAULT_TILESET {
+		return &renderer.Assets.TestTextures
+	}
+
+	if tileset, ok := renderer.Assets.TileSets[name]; ok {
+		return tileset
+	}
+
+	tileset := BuildTileSet(name)
+	renderer.Assets.TileSets[name] = &tileset
+	return &tileset
+}
+
 func BuildTileSet(name string) utils.TileSet {
 	t := time.Now()
 	base := rl.LoadImage(utils.GetAssetPath(utils.TEXTURE, fmt.Sprintf("%v.png", name)))
@@ -190,32 +221,40 @@ func buildTile(parts *[13]*rl.Image, n uint16) *rl.Image {
 	return tile
 }
 
-func GetTile(tileType int) *rl.Texture2D {
-	if tex := &Assets.TileTextures[tileType]; tex.Height != 0 {
+func (renderer *Renderer) GetTile(tileType int) *rl.Texture2D {
+	if tex := &renderer.Assets.TileTextures[tileType]; tex.Height != 0 {
 		return tex
 	} else {
-		return Assets.MissingTexture
+		return renderer.Assets.MissingTexture
 	}
 }
 
-const (
-	PLAYER_IDLE = iota
-	GOBLIN_IDLE = iota
-)
-
-func loadCharacterSprites() []rl.Texture2D {
-	texturelist := make([]rl.Texture2D, 2)
-	texturelist[PLAYER_IDLE] = rl.LoadTexture(utils.GetAssetPath(utils.SPRITE, "player_idle.png"))
-	texturelist[GOBLIN_IDLE] = rl.LoadTexture(utils.GetAssetPath(utils.SPRITE, "goblin_idle.png"))
+// The player's sprite and the ones named by the enemy archetypes
+func loadCharacterSprites(archetypes []*sim.Archetype) map[string]rl.Texture2D {
+	sprites := map[string]rl.Texture2D{
+		sim.PLAYER_SPRITE: rl.LoadTexture(utils.GetAssetPath(utils.SPRITE, sim.PLAYER_SPRITE)),
+	}
+	for _, archetype := range archetypes {
+		if _, ok := sprites[archetype.Sprite]; !ok {
+			sprites[archetype.Sprite] = rl.LoadTexture(utils.GetAssetPath(utils.SPRITE, archetype.Sprite))
+		}
+	}
 
-	return texturelist
+	return sprites
 }
 
-func GetCharacterSprite(state int) *rl.Texture2D {
-	if tex := &Assets.CharacterSprites[state]; tex.Height != 0 {
-		return tex
+// Sprites that weren't loaded with the assets, like the ones of enemies from an older save, are loaded the first time they are drawn
+func (renderer *Renderer) GetCharacterSprite(name string) *rl.Texture2D {
+	tex, ok := renderer.Assets.CharacterSprites[name]
+	if !ok {
+		tex = rl.LoadTexture(utils.GetAssetPath(utils.SPRITE, name))
+		renderer.Assets.CharacterSprites[name] = tex
+	}
+
+	if tex.Height != 0 {
+		return &tex
 	} else {
-		return Assets.MissingTexture
+		return renderer.Assets.MissingTexture
 	}
 }
 
@@ -238,11 +277,11 @@ func loadUISprites() []rl.Texture2D {
 	return texturelist
 }
 
-func GetUISprite(uiAsset int) *rl.Texture2D {
-	if tex := &Assets.UISprites[uiAsset]; tex.Height != 0 {
+func (renderer *Renderer) GetUISprite(uiAsset int) *rl.Texture2D {
+	if tex := &renderer.Assets.UISprites[uiAsset]; tex.Height != 0 {
 		return tex
 	} else {
-		return Assets.MissingTexture
+		return renderer.Assets.MissingTexture
 	}
 }
 
@@ -1,6 +1,7 @@
 package rendering
 
 import (
+	"fmt"
 	"log"
 	"utils"
 
@@ -16,13 +17,14 @@ var (
 	ButtonFocusBackground = rl.NewColor(61, 83, 128, 255)
 )
 
-func DrawMenuButtons(menu int, exitWindow *bool) {
+func (renderer *Renderer) DrawMenuButtons(menu int, exitWindow *bool) {
+	appState := renderer.AppState
 
-	DrawMainText(rl.Vector2{X: float32(appState.Settings.Resolution.X / 2), Y: float32(appState.Settings.Resolution.Y / 6)}, 96.0, "KIIKKUPASKAA", rl.RayWhite)
+	renderer.DrawMainText(rl.Vector2{X: float32(appState.Settings.Resolution.X / 2), Y: float32(appState.Settings.Resolution.Y / 6)}, 96.0, "KIIKKUPASKAA", rl.RayWhite)
 	if appState.Settings.PanelVisible {
-		DrawSettingsPanel()
+		renderer.DrawSettingsPanel()
 	} else {
-		settings := DrawButton(rl.NewVector2(float32(appState.Settings.Resolution.X)/2.0, float32(appState.Settings.Resolution.Y)/2.0+100.0), "SETTINGS")
+		settings := renderer.DrawButton(rl.NewVector2(float32(appState.Settings.Resolution.X)/2.0, float32(appState.Settings.Resolution.Y)/2.0+100.0), "SETTINGS")
 
 		if settings {
 			appState.Settings.PanelVisible = !appState.Settings.PanelVisible
@@ -30,8 +32,18 @@ func DrawMenuButtons(menu int, exitWindow *bool) {
 		topButtonPos := rl.NewVector2(float32(appState.Settings.Resolution.X)/2.0, float32(appState.Settings.Resolution.Y)/2.0+50.0)
 		botButtonPos := rl.NewVector2(float32(appState.Settings.Resolution.X)/2.0, float32(appState.Settings.Resolution.Y)/2.0+150.0)
 		if menu == utils.MAIN_MENU {
-			start := DrawButton(topButtonPos, "START")
-			exit := DrawButton(botButtonPos, "QUIT")
+			renderer.DrawSeedInput(rl.NewVector2(float32(appState.Settings.Resolution.X)/2.0, float32(appState.Settings.Resolution.Y)/2.0-10.0))
+
+			if utils.SaveExists(utils.SAVE_FILE) {
+				continueGame := renderer.DrawButton(rl.NewVector2(float32(appState.Settings.Resolution.X)/2.0, float32(appState.Settings.Resolution.Y)/2.0-60.0), "CONTINUE")
+				if continueGame {
+					appState.ContinueGame = true
+					appState.View = utils.IN_GAME
+				}
+			}
+
+			start := renderer.DrawButton(topButtonPos, "START")
+			exit := renderer.DrawButton(botButtonPos, "QUIT")
 
 			if start {
 				appState.View = utils.IN_GAME
@@ -43,8 +55,15 @@ func DrawMenuButtons(menu int, exitWindow *bool) {
 		}
 
 		if menu == utils.PAUSED {
-			resume := DrawButton(topButtonPos, "RESUME")
-			exit := DrawButton(botButtonPos, "EXIT TO MENU")
+			renderer.DrawSecondaryText(
+				rl.NewVector2(float32(appState.Settings.Resolution.X)/2.0, float32(appState.Settings.Resolution.Y)/2.0-10.0),
+				24.0,
+				fmt.Sprintf("seed %d", appState.ActiveSeed),
+				rl.RayWhite,
+			)
+
+			resume := renderer.DrawButton(topButtonPos, "RESUME")
+			exit := renderer.DrawButton(botButtonPos, "EXIT TO MENU")
 
 			if resume {
 				appState.View = utils.IN_GAME
@@ -57,7 +76,27 @@ func DrawMenuButtons(menu int, exitWindow *bool) {
 	}
 }
 
-func DrawSettingsPanel() {
+func (renderer *Renderer) DrawSeedInput(pos rl.Vector2) {
+	appState := renderer.AppState
+	const width = 200.0
+	const height = 25.0
+
+	renderer.DrawSecondaryText(rl.NewVector2(pos.X-width/2.0-30.0, pos.Y), 24.0, "SEED", rl.RayWhite)
+
+	bounds := rl.NewRectangle(pos.X-width/2.0, pos.Y, width, height)
+	input := rgui.TextBox(bounds, appState.Settings.SeedInput)
+	if input != appState.Settings.SeedInput {
+		appState.Settings.SeedInput = input
+		appState.Settings.Seed = utils.ParseSeed(input)
+	}
+
+	if appState.Settings.SeedInput == "" {
+		rl.DrawText("random", bounds.ToInt32().X+4, bounds.ToInt32().Y+6, 12, SilverAccent)
+	}
+}
+
+func (renderer *Renderer) DrawSettingsPanel() {
+	appState := renderer.AppState
 	appState.Settings.SelectedResolution = 0
 	for i, res := range utils.ResolutionList {
 		if res == utils.ResToString(appState.Settings.Resolution) {
@@ -74,7 +113,7 @@ func DrawSettingsPanel() {
 	rl.DrawRectangleRounded(background, 0.05, 2, PanelBackground)
 	rl.DrawRectangleRoundedLines(background, 0.05, 2, 2.0, GoldAccent)
 
-	DrawSecondaryText(
+	renderer.DrawSecondaryText(
 		rl.NewVector2(appState.Settings.Resolution.ToVec2().X/2.0, appState.Settings.Resolution.ToVec2().Y/2.0-250.0),
 		24.0,
 		"Resolution",
@@ -89,11 +128,11 @@ func DrawSettingsPanel() {
 	)
 
 	appState.Settings.SelectedResolution = rgui.ToggleGroup(resolutionBackground, utils.ResolutionList, appState.Settings.SelectedResolution)
-	if utils.HandleResolutionChange(utils.StringToRes(utils.ResolutionList[appState.Settings.SelectedResolution])) {
+	if appState.Settings.HandleResolutionChange(utils.StringToRes(utils.ResolutionList[appState.Settings.SelectedResolution], appState.Settings.Resolution)) {
 		log.Print("Switched resolution to ", utils.ResolutionList[appState.Settings.SelectedResolution])
 	}
 
-	DrawSecondaryText(
+	renderer.DrawSecondaryText(
 		rl.NewVector2(
 			appState.Settings.Resolution.ToVec2().X/2.0-25.0,
 			appState.Settings.Resolution.ToVec2().Y/2.0+120.0,
@@ -112,7 +151,7 @@ func DrawSettingsPanel() {
 	musicToggle := rgui.CheckBox(musicCheckboxBackground, appState.Settings.Music)
 	if musicToggle != appState.Settings.Music {
 		appState.Settings.Music = musicToggle
-		utils.SaveSettingsFile()
+		appState.Settings.SaveSettingsFile()
 	}
 
 	checkboxTex := SPRITE_CROSS
@@ -121,7 +160,7 @@ func DrawSettingsPanel() {
 	}
 
 	rl.DrawTextureV(
-		appState.RenderAssets.UISprites[checkboxTex],
+		renderer.Assets.UISprites[checkboxTex],
 		rl.NewVector2(musicCheckboxBackground.X, musicCheckboxBackground.Y),
 		rl.White,
 	)
@@ -130,18 +169,18 @@ func DrawSettingsPanel() {
 		appState.Settings.Resolution.ToVec2().X/2.0,
 		appState.Settings.Resolution.ToVec2().Y/2.0+220.0,
 	)
-	if DrawButton(closeButtonPos, "Close") {
+	if renderer.DrawButton(closeButtonPos, "Close") {
 		appState.Settings.PanelVisible = false
 	}
 }
 
-func DrawButton(pos rl.Vector2, text string) bool {
+func (renderer *Renderer) DrawButton(pos rl.Vector2, text string) bool {
 	const width = 100.0
 	const height = 25.0
 	const textPadding = 4
 
 	pos.X -= width / 2.0
-	textHeight := appState.RenderAssets.SecondaryFont.BaseSize
+	textHeight := renderer.Assets.SecondaryFont.BaseSize
 	textWidth := rl.MeasureText(text, textHeight)
 	bounds := rl.NewRectangle(pos.X, pos.Y, width, height)
 
@@ -171,7 +210,7 @@ func DrawButton(pos rl.Vector2, text string) bool {
 		float32(b.X+(b.Width/2)+textPadding),
 		float32(b.Y+((b.Height/2)-(textHeight/2))),
 	)
-	DrawSecondaryText(textPos, float32(textHeight), text, rl.RayWhite)
+	renderer.DrawSecondaryText(textPos, float32(textHeight), text, rl.RayWhite)
 
 	return state == rgui.Clicked
 }
@@ -182,14 +221,14 @@ func DrawDefaultText(pos rl.Vector2, size float32, text string, colour rl.Color)
 	rl.DrawText(text, int32(pos.X), int32(pos.Y), int32(size), colour)
 }
 
-func DrawMainText(pos rl.Vector2, size float32, text string, colour rl.Color) {
+func (renderer *Renderer) DrawMainText(pos rl.Vector2, size float32, text string, colour rl.Color) {
 	width := rl.MeasureText(text, int32(size))
 	pos.X -= float32(width / 2)
-	rl.DrawTextEx(appState.RenderAssets.MainFont, text, pos, size, 1.0, colour)
+	rl.DrawTextEx(renderer.Assets.MainFont, text, pos, size, 1.0, colour)
 }
 
-func DrawSecondaryText(pos rl.Vector2, size float32, text string, colour rl.Color) {
+func (renderer *Renderer) DrawSecondaryText(pos rl.Vector2, size float32, text string, colour rl.Color) {
 	width := rl.MeasureText(text, int32(size))
 	pos.X -= float32(width) / 2.0
-	rl.DrawTextEx(appState.RenderAssets.SecondaryFont, text, pos, size, 1.0, colour)
+	rl.DrawTextEx(renderer.Assets.SecondaryFont, text, pos, size, 1.0, colour)
 }
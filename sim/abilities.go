@@ -0,0 +1,467 @@
+package sim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"fov"
+	"io/ioutil"
+	"log"
+	"math"
+	"strings"
+)
+
+const ABILITIES_FILE = DATA_FOLDER + "abilities.json"
+
+const (
+	ABILITY_THROW = "throw"
+	ABILITY_AREA  = "area"
+	ABILITY_DASH  = "dash"
+	ABILITY_FLARE = "flare"
+)
+
+var abilityKindNames = []string{ABILITY_THROW, ABILITY_AREA, ABILITY_DASH, ABILITY_FLARE}
+
+// Ability is something the player can do at the selection cursor from the hotbar, as described in the abilities file
+type Ability struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// Taken from the player's turn when used
+	Actions  uint8 `json:"actions"`
+	Movement uint8 `json:"movement"`
+	// Rounds before it can be used again, 1 for once a turn
+	Cooldown int `json:"cooldown"`
+	// How far from the player it can be aimed, in tiles, the target has to be in sight too
+	Range float32 `json:"range"`
+	// Tiles around the target an area attack hits or a flare lights
+	Radius float32 `json:"radius,omitempty"`
+	// Rolled instead of the weapon's on every hit, Strength adds to it like on any attack
+	Damage Dice `json:"damage"`
+	// Inflicted on whoever the ability hits, flares roll them on everyone they light
+	Effects []Infliction `json:"effects,omitempty"`
+	// Rounds a flare burns for
+	Rounds int `json:"rounds,omitempty"`
+}
+
+// abilityKind is what an ability does, the data file picks one for every ability
+type abilityKind interface {
+	// check validates the fields of an ability of the kind
+	check(ability *Ability) error
+	// validate checks the target on top of the cost, cooldown and range every ability has
+	validate(world *World, ability *Ability, target IVector2) error
+	use(world *World, ability *Ability, target IVector2)
+}
+
+var abilityKinds = map[string]abilityKind{
+	ABILITY_THROW: throwAbility{},
+	ABILITY_AREA:  areaAbility{},
+	ABILITY_DASH:  dashAbility{},
+	ABILITY_FLARE: flareAbility{},
+}
+
+type abilitiesFile struct {
+	Abilities []*Ability `json:"abilities"`
+}
+
+// LoadAbilities replaces the abilities with the ones in the file
+func (data *Data) LoadAbilities(path string) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	loaded, err := ParseAbilities(path, contents)
+	if err != nil {
+		return err
+	}
+	data.Abilities = loaded
+	log.Printf("Loaded %d abilities from %v", len(loaded), path)
+	return nil
+}
+
+func ParseAbilities(path string, data []byte) ([]*Ability, error) {
+	var file abilitiesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	if len(file.Abilities) == 0 {
+		return nil, fmt.Errorf("%v: no abilities", path)
+	}
+
+	names := make(map[string]bool)
+	for i, ability := range file.Abilities {
+		if err := ability.validate(); err != nil {
+			return nil, fmt.Errorf("%v: ability %d: %v", path, i+1, err)
+		}
+		if names[ability.Name] {
+			return nil, fmt.Errorf("%v: ability %d: %q is defined twice", path, i+1, ability.Name)
+		}
+		names[ability.Name] = true
+	}
+	return file.Abilities, nil
+}
+
+func (ability *Ability) validate() error {
+	kind, ok := abilityKinds[ability.Kind]
+	switch {
+	case ability.Name == "":
+		return fmt.Errorf("missing name")
+	case !ok:
+		return fmt.Errorf("%v has unknown kind %q, expected one of %v", ability.Name, ability.Kind, abilityKindNames)
+	case ability.Cooldown < 0:
+		return fmt.Errorf("%v has a negative cooldown", ability.Name)
+	case ability.Range <= 0:
+		return fmt.Errorf("%v has a range of %v, expected more than 0", ability.Name, ability.Range)
+	case ability.Radius < 0:
+		return fmt.Errorf("%v has a negative radius", ability.Name)
+	case ability.Rounds < 0:
+		return fmt.Errorf("%v burns for negative rounds", ability.Name)
+	}
+
+	for _, infliction := range ability.Effects {
+		if err := infliction.validate(ability.Name, false); err != nil {
+			return err
+		}
+	}
+	return kind.check(ability)
+}
+
+func (ability *Ability) kind() abilityKind {
+	return abilityKinds[ability.Kind]
+}
+
+// Combatant of the player using the ability, its damage and effects instead of the weapon's
+func (ability *Ability) combatant(player *Player) Combatant {
+	combatant := player.Combatant()
+	combatant.Damage = []Dice{ability.Damage}
+	combatant.Inflicts = ability.Effects
+	return combatant
+}
+
+// Costs and cooldown the way the hotbar shows them
+func (ability *Ability) Costs() string {
+	var costs []string
+	if ability.Actions > 0 {
+		costs = append(costs, fmt.Sprintf("actions %d", ability.Actions))
+	}
+	if ability.Movement > 0 {
+		costs = append(costs, fmt.Sprintf("movement %d", ability.Movement))
+	}
+	costs = append(costs, fmt.Sprintf("cooldown %d", ability.Cooldown))
+	return strings.Join(costs, "  ")
+}
+
+// Thrown at a single enemy in range
+type throwAbility struct{}
+
+func (throwAbility) check(ability *Ability) error {
+	if ability.Damage.IsZero() {
+		return fmt.Errorf("%v throws nothing, it needs damage", ability.Name)
+	}
+	return nil
+}
+
+func (throwAbility) validate(world *World, ability *Ability, target IVector2) error {
+	if world.enemyAt(target) == nil {
+		return errors.New("nothing to throw at")
+	}
+	return nil
+}
+
+func (throwAbility) use(world *World, ability *Ability, target IVector2) {
+	attacker := ability.combatant(world.Player)
+	world.strike(&attacker, world.enemyAt(target))
+}
+
+// Hits every enemy within the radius of the target, walls shield them
+type areaAbility struct{}
+
+func (areaAbility) check(ability *Ability) error {
+	if ability.Damage.IsZero() || ability.Radius <= 0 {
+		return fmt.Errorf("%v needs damage and a radius", ability.Name)
+	}
+	return nil
+}
+
+func (areaAbility) validate(world *World, ability *Ability, target IVector2) error {
+	return nil
+}
+
+func (areaAbility) use(world *World, ability *Ability, target IVector2) {
+	attacker := ability.combatant(world.Player)
+	for _, enemy := range world.enemiesIn(world.area(target, ability.Radius)) {
+		world.strike(&attacker, enemy)
+	}
+}
+
+// Jumps the player to the target in a single step, for bleeding too
+type dashAbility struct{}
+
+func (dashAbility) check(ability *Ability) error {
+	if !ability.Damage.IsZero() || len(ability.Effects) > 0 {
+		return fmt.Errorf("%v can't hit anything", ability.Name)
+	}
+	return nil
+}
+
+func (dashAbility) validate(world *World, ability *Ability, target IVector2) error {
+	tile, ok := world.GetMapTile(target)
+	switch {
+	case target == world.Player.Pos:
+		return errors.New("already there")
+	case !ok || tile.Block:
+		return errors.New("the way is blocked")
+	case world.enemyAt(target) != nil:
+		return errors.New("an enemy is in the way")
+	}
+	return nil
+}
+
+func (dashAbility) use(world *World, ability *Ability, target IVector2) {
+	world.Player.Pos = target
+	world.emit(EVENT_MOVED, target, "Dashed to %v", tilePoint(target))
+	world.moved(world.Player)
+}
+
+// Lights up the area around the target for a few rounds, blinding the enemies caught in it
+type flareAbility struct{}
+
+func (flareAbility) check(ability *Ability) error {
+	if ability.Radius <= 0 || ability.Rounds <= 0 {
+		return fmt.Errorf("%v needs a radius and rounds to burn", ability.Name)
+	}
+	return nil
+}
+
+func (flareAbility) validate(world *World, ability *Ability, target IVector2) error {
+	return nil
+}
+
+func (flareAbility) use(world *World, ability *Ability, target IVector2) {
+	world.Flares = append(world.Flares, &Flare{Pos: target, Radius: ability.Radius, Rounds: ability.Rounds})
+	attacker := ability.combatant(world.Player)
+	for _, enemy := range world.enemiesIn(world.area(target, ability.Radius)) {
+		world.inflict(&attacker, enemy, world.rng)
+	}
+}
+
+// Flare lights the tiles around it until it burns out, the player sees whatever it lights
+type Flare struct {
+	Pos    IVector2 `json:"pos"`
+	Radius float32  `json:"radius"`
+	// Rounds left, counting the current one
+	Rounds int `json:"rounds"`
+	// Updated with the player's sight
+	Sight fov.Set `json:"-"`
+}
+
+// Light the flare gives to the tile at pos
+func (flare *Flare) light(pos IVector2) uint8 {
+	if !flare.Sight[tilePoint(pos)] {
+		return 0
+	}
+	return calculateLightLevel(distance(flare.Pos, pos), uint8(flare.Radius)+1)
+}
+
+// The brightest light of the flares at pos
+func (world *World) flareLight(pos IVector2) uint8 {
+	light := uint8(0)
+	for _, flare := range world.Flares {
+		if l := flare.light(pos); l > light {
+			light = l
+		}
+	}
+	return light
+}
+
+// area is the tiles within radius of center with nothing blocking the way to them, walls at the edge included
+func (world *World) area(center IVector2, radius float32) fov.Set {
+	set := fov.Compute(tilePoint(center), int(math.Ceil(float64(radius))), world.isOpaque)
+	for point := range set {
+		if distance(center, NewIVector2(int32(point.X)*TILE_SIZE, int32(point.Y)*TILE_SIZE)) > radius {
+			delete(set, point)
+		}
+	}
+	return set
+}
+
+// TilesWithin lists the positions of the tiles in area, for previews around the selection cursor
+func (world *World) TilesWithin(center IVector2, radius float32) []IVector2 {
+	var tiles []IVector2
+	for point := range world.area(center, radius) {
+		pos := NewIVector2(int32(point.X)*TILE_SIZE, int32(point.Y)*TILE_SIZE)
+		if _, ok := world.GetMapTile(pos); ok {
+			tiles = append(tiles, pos)
+		}
+	}
+	return tiles
+}
+
+// Living enemies standing in the area, in the order of world.Enemies so the rolls come out the same every time
+func (world *World) enemiesIn(area fov.Set) []*Enemy {
+	var enemies []*Enemy
+	for _, enemy := range world.Enemies {
+		if enemy.Health > 0.0 && area[tilePoint(enemy.Pos)] {
+			enemies = append(enemies, enemy)
+		}
+	}
+	return enemies
+}
+
+// AbilityBar is the player's hotbar, every ability of the world's data is on it
+type AbilityBar struct {
+	// Index in Data.Abilities of the ability the hotbar points at
+	Selected int
+	// Rounds until an ability can be used again by its name, ready abilities are left out
+	Cooldowns map[string]int
+}
+
+// SelectedAbility is the one the hotbar points at among the world's abilities, nil if there are none
+func (bar *AbilityBar) SelectedAbility(abilities []*Ability) *Ability {
+	if bar.Selected < 0 || bar.Selected >= len(abilities) {
+		return nil
+	}
+	return abilities[bar.Selected]
+}
+
+// Select moves the selection by offset, wrapping around at either end
+func (bar *AbilityBar) Select(abilities []*Ability, offset int) {
+	count := len(abilities)
+	if count == 0 {
+		bar.Selected = 0
+		return
+	}
+	bar.Selected = ((bar.Selected+offset)%count + count) % count
+}
+
+func (bar *AbilityBar) setCooldown(name string, rounds int) {
+	if rounds <= 0 {
+		delete(bar.Cooldowns, name)
+		return
+	}
+	if bar.Cooldowns == nil {
+		bar.Cooldowns = make(map[string]int)
+	}
+	bar.Cooldowns[name] = rounds
+}
+
+// tickAbilities counts down the cooldowns and burns down the flares once a round is over
+func (world *World) tickAbilities() {
+	bar := &world.Player.Abilities
+	for name, rounds := range bar.Cooldowns {
+		bar.setCooldown(name, rounds-1)
+	}
+
+	burning := world.Flares[:0]
+	for _, flare := range world.Flares {
+		flare.Rounds--
+		if flare.Rounds > 0 {
+			burning = append(burning, flare)
+		}
+	}
+	world.Flares = burning
+}
+
+// The state of an enemy before an ability hit it
+type enemySnapshot struct {
+	enemy   *Enemy
+	health  float32
+	effects Effects
+}
+
+// AbilityCommand uses an ability of the hotbar at the target
+type AbilityCommand struct {
+	Ability *Ability
+	Target  IVector2
+	turn    TurnData
+	pos     IVector2
+	// Set when a dash made the player bleed, which can't be taken back like with moves
+	hurt bool
+	// Kills are worth experience, taken back on undo
+	progress Progress
+	enemies  []enemySnapshot
+	flares   int
+	// Undoing puts the random numbers back too, like with attacks
+	rngSeed  int64
+	rngCalls uint64
+}
+
+// NewAbilityCommand uses the ability selected on the hotbar at the selection cursor
+func NewAbilityCommand(world *World) *AbilityCommand {
+	return &AbilityCommand{Ability: world.Player.Abilities.SelectedAbility(world.Data.Abilities), Target: world.Selection.Pos}
+}
+
+func (command *AbilityCommand) Validate(world *World) error {
+	turn := &world.Player.Turn
+	if turn.Done {
+		return errTurnOver
+	}
+	ability := command.Ability
+	if ability == nil {
+		return errors.New("no ability selected")
+	}
+	if rounds := world.Player.Abilities.Cooldowns[ability.Name]; rounds > 0 {
+		return fmt.Errorf("%v is ready in %d rounds", ability.Name, rounds)
+	}
+	if turn.Actions < ability.Actions {
+		return errors.New("not enough actions left")
+	}
+	if turn.Movement < ability.Movement {
+		return errors.New("not enough movement left")
+	}
+	if !world.area(world.Player.Pos, ability.Range)[tilePoint(command.Target)] {
+		return errors.New("out of range")
+	}
+	return ability.kind().validate(world, ability, command.Target)
+}
+
+func (command *AbilityCommand) Execute(world *World) {
+	player := world.Player
+	ability := command.Ability
+	command.turn = player.Turn
+	command.pos = player.Pos
+	command.progress = player.Progress
+	command.flares = len(world.Flares)
+	command.rngSeed, command.rngCalls = world.rngSource.State()
+	command.enemies = nil
+	for _, enemy := range world.Enemies {
+		if enemy.Health > 0.0 {
+			command.enemies = append(command.enemies, enemySnapshot{enemy, enemy.Health, enemy.Effects.copy()})
+		}
+	}
+
+	player.Turn.Actions -= ability.Actions
+	player.Turn.Movement -= ability.Movement
+	player.Abilities.setCooldown(ability.Name, ability.Cooldown)
+	world.emit(EVENT_USED_ABILITY, command.Target, "Used %v at %v", ability.Name, tilePoint(command.Target))
+	health := player.Health
+	ability.kind().use(world, ability, command.Target)
+	command.hurt = player.Health < health
+}
+
+func (command *AbilityCommand) final() bool {
+	return command.hurt
+}
+
+func (command *AbilityCommand) Undo(world *World) {
+	player := world.Player
+	player.Turn = command.turn
+	player.Pos = command.pos
+	player.restoreProgress(command.progress)
+	player.Abilities.setCooldown(command.Ability.Name, 0)
+	world.Flares = world.Flares[:command.flares]
+	world.restoreRng(command.rngSeed, command.rngCalls)
+	for _, snapshot := range command.enemies {
+		snapshot.enemy.Health = snapshot.health
+		snapshot.enemy.Effects = snapshot.effects
+		world.reviveEnemy(snapshot.enemy)
+	}
+}
+
+func (command *AbilityCommand) String() string {
+	name := "nothing"
+	if command.Ability != nil {
+		name = command.Ability.Name
+	}
+	return fmt.Sprintf("use %v at %v", name, tilePoint(command.Target))
+}
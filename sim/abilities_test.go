@@ -0,0 +1,212 @@
+package sim
+
+import (
+	"testing"
+)
+
+const abilityTestMap = `
+@@@@@@@@@@
+@________@
+@P___g_g_@
+@_____@@@@
+@_____@__@
+@@@@@@@@@@
+`
+
+// A world with the shipped abilities, the player's turn started and the named ability selected
+func newAbilityTestWorld(t *testing.T, name string) *World {
+	world := newTestWorld(t, 1, abilityTestMap)
+	world.Data = loadShippedData(t)
+	world.Player.StartTurn()
+	for i, ability := range world.Data.Abilities {
+		if ability.Kind == name {
+			world.Player.Abilities.Selected = i
+			return world
+		}
+	}
+	t.Fatalf("no %v ability is shipped", name)
+	return nil
+}
+
+func useAbility(world *World, target IVector2) error {
+	world.Selection = SelectionMode{Using: true, Pos: target}
+	return world.Execute(NewAbilityCommand(world))
+}
+
+func TestShippedAbilities(t *testing.T) {
+	kinds := make(map[string]bool)
+	for _, ability := range loadShippedData(t).Abilities {
+		kinds[ability.Kind] = true
+	}
+	for _, kind := range abilityKindNames {
+		if !kinds[kind] {
+			t.Errorf("no %v ability is shipped", kind)
+		}
+	}
+}
+
+func TestParseAbilitiesErrors(t *testing.T) {
+	cases := map[string]string{
+		"no abilities":      `{"abilities": []}`,
+		"missing name":      `{"abilities": [{"kind": "throw", "range": 3, "damage": "1d4"}]}`,
+		"unknown kind":      `{"abilities": [{"name": "a", "kind": "teleport", "range": 3}]}`,
+		"no range":          `{"abilities": [{"name": "a", "kind": "throw", "damage": "1d4"}]}`,
+		"negative cooldown": `{"abilities": [{"name": "a", "kind": "throw", "range": 3, "damage": "1d4", "cooldown": -1}]}`,
+		"harmless throw":    `{"abilities": [{"name": "a", "kind": "throw", "range": 3}]}`,
+		"pointless area":    `{"abilities": [{"name": "a", "kind": "area", "range": 3, "damage": "1d4"}]}`,
+		"dash that hits":    `{"abilities": [{"name": "a", "kind": "dash", "range": 3, "damage": "1d4"}]}`,
+		"flare burning out": `{"abilities": [{"name": "a", "kind": "flare", "range": 3, "radius": 2}]}`,
+		"effect chance":     `{"abilities": [{"name": "a", "kind": "throw", "range": 3, "damage": "1d4", "effects": [{"effect": "stun", "turns": 1}]}]}`,
+		"defined twice":     `{"abilities": [{"name": "a", "kind": "dash", "range": 3}, {"name": "a", "kind": "dash", "range": 2}]}`,
+	}
+	for name, data := range cases {
+		if _, err := ParseAbilities("test.json", []byte(data)); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
+
+func TestThrowAbility(t *testing.T) {
+	world := newAbilityTestWorld(t, ABILITY_THROW)
+	ability := world.Player.Abilities.SelectedAbility(world.Data.Abilities)
+	enemy := world.enemyAt(tilePos(5, 2))
+	health, actions := enemy.Health, world.Player.Turn.Actions
+	rollCritical(world)
+
+	if err := useAbility(world, tilePos(4, 2)); err == nil {
+		t.Error("threw at an empty tile")
+	}
+	if err := useAbility(world, enemy.Pos); err != nil {
+		t.Fatal(err)
+	}
+	if enemy.Health >= health || world.Player.Turn.Actions != actions-ability.Actions {
+		t.Errorf("the throw left the enemy with %v health and the player with %d actions", enemy.Health, world.Player.Turn.Actions)
+	}
+	if err := useAbility(world, enemy.Pos); err == nil {
+		t.Error("threw again before the cooldown was over")
+	}
+
+	world.Commands.Undo(world)
+	if enemy.Health != health || world.Player.Turn.Actions != actions || world.Player.Abilities.Cooldowns[ability.Name] != 0 {
+		t.Error("undo didn't take back the throw")
+	}
+}
+
+func TestAbilityRange(t *testing.T) {
+	world := newAbilityTestWorld(t, ABILITY_THROW)
+	// Too far away
+	if err := useAbility(world, tilePos(7, 2)); err == nil {
+		t.Error("threw further than the range")
+	}
+
+	// Behind the wall
+	world.Player.Pos = tilePos(7, 4)
+	if err := useAbility(world, tilePos(7, 2)); err == nil {
+		t.Error("threw through a wall")
+	}
+}
+
+func TestCooldownsTickByRound(t *testing.T) {
+	world := newAbilityTestWorld(t, ABILITY_DASH)
+	ability := world.Player.Abilities.SelectedAbility(world.Data.Abilities)
+	if err := useAbility(world, tilePos(3, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	for round := ability.Cooldown; round > 0; round-- {
+		if rounds := world.Player.Abilities.Cooldowns[ability.Name]; rounds != round {
+			t.Fatalf("expected %d rounds of cooldown, got %d", round, rounds)
+		}
+		world.tickAbilities()
+	}
+	if _, ok := world.Player.Abilities.Cooldowns[ability.Name]; ok {
+		t.Error("the cooldown didn't run out")
+	}
+}
+
+func TestAreaAbility(t *testing.T) {
+	world := newAbilityTestWorld(t, ABILITY_AREA)
+	world.Enemies = append(world.Enemies, goblinArchetype.NewEnemy(tilePos(5, 1), 0))
+	world.Events.Flush()
+
+	if err := useAbility(world, tilePos(5, 2)); err != nil {
+		t.Fatal(err)
+	}
+	attacks := 0
+	for _, event := range world.Events.Flush() {
+		if event.Kind == EVENT_ATTACKED || event.Kind == EVENT_MISSED {
+			attacks++
+		}
+	}
+	// The goblin two tiles away is out of the blast
+	if attacks != 2 {
+		t.Errorf("the blast attacked %d enemies", attacks)
+	}
+}
+
+func TestDashAbility(t *testing.T) {
+	world := newAbilityTestWorld(t, ABILITY_DASH)
+	ability := world.Player.Abilities.SelectedAbility(world.Data.Abilities)
+	movement := world.Player.Turn.Movement
+
+	if err := useAbility(world, tilePos(0, 2)); err == nil {
+		t.Error("dashed into a wall")
+	}
+	if err := useAbility(world, tilePos(4, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if world.Player.Pos != tilePos(4, 2) || world.Player.Turn.Movement != movement-ability.Movement {
+		t.Errorf("dashed to %v with %d movement left", tilePoint(world.Player.Pos), world.Player.Turn.Movement)
+	}
+
+	world.Commands.Undo(world)
+	if world.Player.Pos != tilePos(1, 2) || world.Player.Turn.Movement != movement {
+		t.Error("undo didn't take back the dash")
+	}
+}
+
+func TestFlareAbility(t *testing.T) {
+	world := newAbilityTestWorld(t, ABILITY_FLARE)
+	ability := world.Player.Abilities.SelectedAbility(world.Data.Abilities)
+	world.Player.Stats.Visibility = 1
+	world.updatePlayerSight()
+	if world.PlayerSight[tilePoint(tilePos(5, 2))] {
+		t.Fatal("the player sees the goblin without the flare")
+	}
+
+	if err := useAbility(world, tilePos(4, 2)); err != nil {
+		t.Fatal(err)
+	}
+	world.updatePlayerSight()
+	if !world.enemyAt(tilePos(5, 2)).VisibleToPlayer(world) {
+		t.Error("the flare didn't light up the goblin")
+	}
+
+	for round := 0; round < ability.Rounds; round++ {
+		world.tickAbilities()
+	}
+	if len(world.Flares) != 0 {
+		t.Errorf("the flare still burns after %d rounds", ability.Rounds)
+	}
+}
+
+func TestAreaAbilityKillsSeveral(t *testing.T) {
+	world := newAbilityTestWorld(t, ABILITY_AREA)
+	world.Player.Pos = tilePos(3, 1)
+	world.Player.Stats.Dexterity = 200
+	for _, enemy := range world.Enemies {
+		enemy.Health = 0.01
+	}
+
+	if err := useAbility(world, tilePos(6, 2)); err != nil {
+		t.Fatal(err)
+	}
+	world.update(0)
+	world.update(0)
+	if len(world.Enemies) != 0 || world.Kills != 2 {
+		t.Errorf("%d enemies left and %d kills after the blast", len(world.Enemies), world.Kills)
+	}
+	if len(world.VisibleEnemies) != 0 {
+		t.Error("dead enemies are still visible")
+	}
+}
@@ -0,0 +1,220 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+)
+
+// Data files the front-end loads at startup
+const DATA_FOLDER = "assets/data/"
+const ARCHETYPES_FILE = DATA_FOLDER + "enemies.json"
+
+// Stat points added to enemies for every floor below the first
+const ENEMY_STATS_PER_DEPTH = 1
+
+// Enemies without a behaviour chase the player
+const DEFAULT_BEHAVIOUR = "chase"
+
+// Archetype is a kind of enemy, every enemy of the kind starts out the same apart from the depth bonus
+type Archetype struct {
+	Name      string        `json:"name"`
+	Sprite    string        `json:"sprite"`
+	Stats     Stats         `json:"stats"`
+	Health    HealthFormula `json:"health"`
+	Behaviour string        `json:"behaviour"`
+	// Attack range in tiles, 0 for melee
+	Range float32 `json:"range"`
+	// Names of the items every enemy of the kind has equipped, at most one per slot
+	Gear []string `json:"gear"`
+	// The items named by Gear, looked up when the archetype is validated
+	gear []*ItemKind
+
+	// Relative chance of being picked among the archetypes that can appear on a floor,
+	// WeightPerDepth is added for every floor below MinDepth
+	Weight         float64 `json:"weight"`
+	WeightPerDepth float64 `json:"weightPerDepth"`
+	MinDepth       int     `json:"minDepth"`
+	// 0 for no limit
+	MaxDepth int `json:"maxDepth"`
+}
+
+// Max health is Base + PerVitality for every point of Vitality
+type HealthFormula struct {
+	Base        float32 `json:"base"`
+	PerVitality float32 `json:"perVitality"`
+}
+
+func (formula HealthFormula) MaxHealth(stats *Stats) float32 {
+	return formula.Base + formula.PerVitality*float32(stats.Vitality)
+}
+
+// The built-in goblin, the only archetype of DefaultData
+var goblinArchetype = Archetype{
+	Name:   "goblin",
+	Sprite: "goblin_idle.png",
+	Stats: Stats{
+		Movement:   4,
+		Visibility: 6,
+		Vitality:   5,
+		Strength:   3,
+		Dexterity:  5,
+	},
+	Health:    HealthFormula{PerVitality: 2.63},
+	Behaviour: DEFAULT_BEHAVIOUR,
+	Weight:    1,
+}
+
+type archetypesFile struct {
+	Enemies []*Archetype `json:"enemies"`
+}
+
+// LoadArchetypes replaces the archetypes with the ones in the file, the items they carry have to be loaded first
+func (data *Data) LoadArchetypes(path string) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	loaded, err := ParseArchetypes(path, contents, data.Items)
+	if err != nil {
+		return err
+	}
+	data.Archetypes = loaded
+	log.Printf("Loaded %d enemy archetypes from %v", len(loaded), path)
+	return nil
+}
+
+// ParseArchetypes looks the gear of the archetypes up from items
+func ParseArchetypes(path string, data []byte, items []*ItemKind) ([]*Archetype, error) {
+	var file archetypesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	if len(file.Enemies) == 0 {
+		return nil, fmt.Errorf("%v: no enemies", path)
+	}
+
+	names := make(map[string]bool)
+	for i, archetype := range file.Enemies {
+		if archetype.Behaviour == "" {
+			archetype.Behaviour = DEFAULT_BEHAVIOUR
+		}
+		if err := archetype.validate(items); err != nil {
+			return nil, fmt.Errorf("%v: enemy %d: %v", path, i+1, err)
+		}
+		if names[archetype.Name] {
+			return nil, fmt.Errorf("%v: enemy %d: %q is defined twice", path, i+1, archetype.Name)
+		}
+		names[archetype.Name] = true
+	}
+	return file.Enemies, nil
+}
+
+func (archetype *Archetype) validate(items []*ItemKind) error {
+	switch {
+	case archetype.Name == "":
+		return fmt.Errorf("missing name")
+	case archetype.Sprite == "":
+		return fmt.Errorf("%v has no sprite", archetype.Name)
+	case archetype.Health.MaxHealth(&archetype.Stats) <= 0:
+		return fmt.Errorf("%v has no health", archetype.Name)
+	case behaviours[archetype.Behaviour] == nil:
+		return fmt.Errorf("%v has unknown behaviour %q", archetype.Name, archetype.Behaviour)
+	case archetype.Range < 0:
+		return fmt.Errorf("%v has a negative range", archetype.Name)
+	case archetype.Weight < 0:
+		return fmt.Errorf("%v has a negative weight", archetype.Name)
+	case archetype.MinDepth < 0 || (archetype.MaxDepth != 0 && archetype.MaxDepth < archetype.MinDepth):
+		return fmt.Errorf("%v has an invalid depth range %d-%d", archetype.Name, archetype.MinDepth, archetype.MaxDepth)
+	}
+
+	archetype.gear = nil
+	slots := make(map[ItemCategory]bool)
+	for _, name := range archetype.Gear {
+		kind, ok := findItemKind(items, name)
+		if !ok {
+			return fmt.Errorf("%v has unknown gear %q", archetype.Name, name)
+		}
+		if !kind.Equippable() {
+			return fmt.Errorf("%v can't equip %v", archetype.Name, name)
+		}
+		if slots[kind.Category] {
+			return fmt.Errorf("%v has more than one %v", archetype.Name, kind.Category)
+		}
+		slots[kind.Category] = true
+		archetype.gear = append(archetype.gear, kind)
+	}
+	return nil
+}
+
+// NewEnemy makes an enemy of the archetype, stronger the deeper it is
+func (archetype *Archetype) NewEnemy(pos IVector2, depth int) *Enemy {
+	stats := archetype.Stats
+	bonus := uint8(depth * ENEMY_STATS_PER_DEPTH)
+	stats.Vitality += bonus
+	stats.Strength += bonus
+	stats.Dexterity += bonus / 2
+
+	enemy := Enemy{
+		Kind:               archetype.Name,
+		Sprite:             archetype.Sprite,
+		Behaviour:          archetype.Behaviour,
+		Pos:                pos,
+		Range:              archetype.Range,
+		LastKnownPlayerPos: pos,
+		Home:               pos,
+		Stats:              stats,
+		Turn:               DefaultEnemyTurn(),
+	}
+	for _, kind := range archetype.gear {
+		item := kind.NewItem()
+		item.Equipped = true
+		enemy.Gear = append(enemy.Gear, item)
+	}
+	effective := enemy.EffectiveStats()
+	enemy.MaxHealth = archetype.Health.MaxHealth(&effective)
+	enemy.Health = enemy.MaxHealth
+	return &enemy
+}
+
+// Weight of the archetype on a floor, 0 if it doesn't appear there
+func (archetype *Archetype) weightAt(depth int) float64 {
+	if depth < archetype.MinDepth || (archetype.MaxDepth != 0 && depth > archetype.MaxDepth) {
+		return 0
+	}
+	weight := archetype.Weight + archetype.WeightPerDepth*float64(depth-archetype.MinDepth)
+	if weight < 0 {
+		return 0
+	}
+	return weight
+}
+
+// SpawnTable picks the archetypes of randomly placed enemies on a floor
+type SpawnTable struct {
+	Archetypes []*Archetype
+	Weights    []float64
+	total      float64
+}
+
+func (data *Data) NewSpawnTable(depth int) *SpawnTable {
+	var table SpawnTable
+	for _, archetype := range data.Archetypes {
+		if weight := archetype.weightAt(depth); weight > 0 {
+			table.Archetypes = append(table.Archetypes, archetype)
+			table.Weights = append(table.Weights, weight)
+			table.total += weight
+		}
+	}
+	return &table
+}
+
+// Pick returns nil if nothing can appear on the floor
+func (table *SpawnTable) Pick(rng *rand.Rand) *Archetype {
+	if table.total <= 0 {
+		return nil
+	}
+	return table.Archetypes[weightedPick(rng, table.Weights, table.total)]
+}
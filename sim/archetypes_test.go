@@ -0,0 +1,119 @@
+package sim
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// The items, archetypes and abilities the game ships with
+func loadShippedData(t *testing.T) *Data {
+	data := DefaultData()
+	if err := data.LoadItems("../" + ITEMS_FILE); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.LoadArchetypes("../" + ARCHETYPES_FILE); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.LoadAbilities("../" + ABILITIES_FILE); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestShippedArchetypes(t *testing.T) {
+	// The levels in assets place goblins
+	if _, ok := loadShippedData(t).GetArchetype(goblinArchetype.Name); !ok {
+		t.Error("no goblin archetype")
+	}
+}
+
+func TestParseArchetypesErrors(t *testing.T) {
+	cases := map[string]string{
+		"no enemies":        `{"enemies": []}`,
+		"missing name":      `{"enemies": [{"sprite": "a.png", "stats": {"vitality": 1}, "health": {"perVitality": 1}}]}`,
+		"missing sprite":    `{"enemies": [{"name": "a", "stats": {"vitality": 1}, "health": {"perVitality": 1}}]}`,
+		"no health":         `{"enemies": [{"name": "a", "sprite": "a.png"}]}`,
+		"unknown behaviour": `{"enemies": [{"name": "a", "sprite": "a.png", "health": {"base": 1}, "behaviour": "dance"}]}`,
+		"negative weight":   `{"enemies": [{"name": "a", "sprite": "a.png", "health": {"base": 1}, "weight": -1}]}`,
+		"depth range":       `{"enemies": [{"name": "a", "sprite": "a.png", "health": {"base": 1}, "minDepth": 3, "maxDepth": 2}]}`,
+		"defined twice":     `{"enemies": [{"name": "a", "sprite": "a.png", "health": {"base": 1}}, {"name": "a", "sprite": "a.png", "health": {"base": 1}}]}`,
+		"unknown gear":      `{"enemies": [{"name": "a", "sprite": "a.png", "health": {"base": 1}, "gear": ["spoon"]}]}`,
+		"unequippable gear": `{"enemies": [{"name": "a", "sprite": "a.png", "health": {"base": 1}, "gear": ["bread"]}]}`,
+		"two weapons":       `{"enemies": [{"name": "a", "sprite": "a.png", "health": {"base": 1}, "gear": ["rusty dagger", "short sword"]}]}`,
+	}
+	items := loadShippedData(t).Items
+	for name, data := range cases {
+		if _, err := ParseArchetypes("test.json", []byte(data), items); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
+
+func TestSpawnTableDepths(t *testing.T) {
+	common := &Archetype{Name: "common", Weight: 3, WeightPerDepth: -1}
+	deep := &Archetype{Name: "deep", Weight: 1, WeightPerDepth: 1, MinDepth: 2}
+	shallow := &Archetype{Name: "shallow", Weight: 1, MaxDepth: 1}
+	data := &Data{Archetypes: []*Archetype{common, deep, shallow}}
+
+	cases := []struct {
+		depth    int
+		expected map[*Archetype]float64
+	}{
+		{0, map[*Archetype]float64{common: 3, shallow: 1}},
+		{1, map[*Archetype]float64{common: 2, shallow: 1}},
+		{2, map[*Archetype]float64{common: 1, deep: 1}},
+		{4, map[*Archetype]float64{deep: 3}},
+	}
+	for _, c := range cases {
+		table := data.NewSpawnTable(c.depth)
+		if len(table.Archetypes) != len(c.expected) {
+			t.Errorf("depth %d: expected %d archetypes, got %d", c.depth, len(c.expected), len(table.Archetypes))
+			continue
+		}
+		for i, archetype := range table.Archetypes {
+			if weight, ok := c.expected[archetype]; !ok || weight != table.Weights[i] {
+				t.Errorf("depth %d: %v has weight %v, expected %v", c.depth, archetype.Name, table.Weights[i], weight)
+			}
+		}
+	}
+
+	// Picks follow the weights
+	table := data.NewSpawnTable(0)
+	rng := rand.New(rand.NewSource(1))
+	counts := make(map[*Archetype]int)
+	for i := 0; i < 4000; i++ {
+		counts[table.Pick(rng)]++
+	}
+	if counts[common] < 2800 || counts[common] > 3200 || counts[deep] != 0 {
+		t.Errorf("picks don't follow the weights: common %d, shallow %d, deep %d", counts[common], counts[shallow], counts[deep])
+	}
+
+	data.Archetypes = nil
+	if data.NewSpawnTable(0).Pick(rng) != nil {
+		t.Error("picked from an empty table")
+	}
+}
+
+func TestArchetypeNewEnemy(t *testing.T) {
+	archetype := &Archetype{
+		Name:      "brute",
+		Sprite:    "brute.png",
+		Stats:     Stats{Vitality: 4, Strength: 2, Dexterity: 2},
+		Health:    HealthFormula{Base: 10, PerVitality: 2},
+		Behaviour: DEFAULT_BEHAVIOUR,
+	}
+
+	enemy := archetype.NewEnemy(NewIVector2(TILE_SIZE, TILE_SIZE), 2)
+	if enemy.Kind != "brute" || enemy.Sprite != "brute.png" || enemy.Behaviour != DEFAULT_BEHAVIOUR {
+		t.Errorf("enemy doesn't match its archetype: %+v", *enemy)
+	}
+	if enemy.Stats.Vitality != 6 || enemy.Stats.Strength != 4 || enemy.Stats.Dexterity != 3 {
+		t.Errorf("stats weren't scaled to the depth: %+v", enemy.Stats)
+	}
+	if enemy.Health != 22 {
+		t.Errorf("expected 22 health, got %v", enemy.Health)
+	}
+	if archetype.Stats.Vitality != 4 {
+		t.Error("the archetype itself was changed")
+	}
+}
@@ -0,0 +1,310 @@
+package sim
+
+// Fleeing enemies run once their health drops below this share of their max health
+const FLEE_HEALTH = 0.3
+
+// Kiting enemies back off when the player gets closer than this, in tiles
+const KITE_DISTANCE = 2.5
+
+// Guards only go after the player this far from their post, in tiles
+const GUARD_LEASH = 5.0
+
+// A pack enemy that spots the player tells everyone within this many tiles
+const PACK_ALERT_RANGE = 8.0
+
+// Patrols walk between their home and up to this many other points
+const PATROL_WAYPOINTS = 3
+
+// Tiles from home the patrol points are picked within
+const PATROL_RADIUS = 6
+
+// Behaviour decides how an enemy acts on its turn.
+// Behaviours are shared by every enemy using them, anything they need to remember is kept on the Enemy.
+type Behaviour interface {
+	// Whether the enemy attacks when the player is in range
+	Attacks(world *World, enemy *Enemy) bool
+	// Spends the enemy's movement, at least a point of it or all of it if there is nothing to do
+	Move(world *World, enemy *Enemy)
+}
+
+// Archetypes pick their behaviour by the name
+var behaviours = map[string]Behaviour{
+	DEFAULT_BEHAVIOUR: ChaseBehaviour{},
+	"patrol":          PatrolBehaviour{},
+	"guard":           GuardBehaviour{},
+	"flee":            FleeBehaviour{},
+	"kite":            KiteBehaviour{},
+	"pack":            PackBehaviour{},
+}
+
+func (enemy *Enemy) behaviour() Behaviour {
+	if behaviour, ok := behaviours[enemy.Behaviour]; ok {
+		return behaviour
+	}
+	return behaviours[DEFAULT_BEHAVIOUR]
+}
+
+// An enemy whose LastKnownPlayerPos is somewhere else is on its way there
+func (enemy *Enemy) hasTarget() bool {
+	return enemy.LastKnownPlayerPos != enemy.Pos
+}
+
+func (enemy *Enemy) forgetTarget() {
+	enemy.LastKnownPlayerPos = enemy.Pos
+}
+
+// Moves the enemy to a neighbouring tile, false if it is blocked or someone stands there
+func (enemy *Enemy) stepTo(world *World, pos IVector2) bool {
+	tile, ok := world.GetMapTile(pos)
+	if !ok || tile.Block || pos == world.Player.Pos || world.enemyAt(pos) != nil {
+		return false
+	}
+
+	enemy.Pos = pos
+	enemy.Turn.Movement--
+	world.moved(enemy)
+	return true
+}
+
+// Takes a step along the way to target, false if the enemy can't get any closer
+func (enemy *Enemy) stepTowards(world *World, target IVector2) bool {
+	path, _ := enemy.PathTo(world, target)
+	enemy.Path = path
+	if len(path) == 0 || path[0] == world.Player.Pos {
+		return false
+	}
+	enemy.Path = path[1:]
+	return enemy.stepTo(world, path[0])
+}
+
+// The free neighbouring tile furthest from pos, if it is further away than where the enemy stands
+func (enemy *Enemy) awayFrom(world *World, pos IVector2) (IVector2, bool) {
+	best := enemy.Pos
+	bestDistance := distance(enemy.Pos, pos)
+	for _, dir := range [4]IVector2{{X: -TILE_SIZE}, {X: TILE_SIZE}, {Y: -TILE_SIZE}, {Y: TILE_SIZE}} {
+		next := NewIVector2(enemy.Pos.X+dir.X, enemy.Pos.Y+dir.Y)
+		tile, ok := world.GetMapTile(next)
+		if !ok || tile.Block || next == world.Player.Pos || world.enemyAt(next) != nil {
+			continue
+		}
+		if d := distance(next, pos); d > bestDistance {
+			best, bestDistance = next, d
+		}
+	}
+	return best, best != enemy.Pos
+}
+
+// ChaseBehaviour goes after the player when it sees them and to where it saw them last, wandering around otherwise
+type ChaseBehaviour struct{}
+
+func (ChaseBehaviour) Attacks(world *World, enemy *Enemy) bool {
+	return true
+}
+
+func (ChaseBehaviour) Move(world *World, enemy *Enemy) {
+	if enemy.CanSeePlayer(world) {
+		if enemy.DistanceToPlayer(world) <= enemy.AttackRange() {
+			enemy.Turn.Movement = 0
+			return
+		}
+		enemy.LastKnownPlayerPos = world.Player.Pos
+	}
+
+	if enemy.hasTarget() {
+		pursue(world, enemy)
+	} else {
+		wander(world, enemy)
+	}
+}
+
+func pursue(world *World, enemy *Enemy) {
+	path, _ := enemy.PathTo(world, enemy.LastKnownPlayerPos)
+	enemy.Path = path
+	if len(path) == 0 {
+		// Got as close as possible, start wandering around from here
+		enemy.forgetTarget()
+		enemy.Turn.Movement = 0
+		return
+	}
+	if path[0] == world.Player.Pos {
+		enemy.Turn.Movement = 0
+		return
+	}
+	enemy.Path = path[1:]
+	enemy.stepTo(world, path[0])
+}
+
+// Steps in a random direction, blocked steps are retried on the next action
+func wander(world *World, enemy *Enemy) {
+	pos := enemy.Pos
+	switch world.rng.Intn(4) {
+	case 0:
+		pos.X -= TILE_SIZE
+	case 1:
+		pos.X += TILE_SIZE
+	case 2:
+		pos.Y -= TILE_SIZE
+	case 3:
+		pos.Y += TILE_SIZE
+	}
+
+	if enemy.stepTo(world, pos) {
+		enemy.forgetTarget()
+	}
+}
+
+// PatrolBehaviour walks a round between its home and a few points around it until it has someone to chase
+type PatrolBehaviour struct{}
+
+func (PatrolBehaviour) Attacks(world *World, enemy *Enemy) bool {
+	return true
+}
+
+func (PatrolBehaviour) Move(world *World, enemy *Enemy) {
+	if enemy.CanSeePlayer(world) || enemy.hasTarget() {
+		ChaseBehaviour{}.Move(world, enemy)
+		return
+	}
+
+	if len(enemy.Waypoints) == 0 {
+		planPatrol(world, enemy)
+	}
+	if enemy.Pos == enemy.Waypoints[enemy.Waypoint%len(enemy.Waypoints)] {
+		enemy.Waypoint = (enemy.Waypoint + 1) % len(enemy.Waypoints)
+	}
+
+	if enemy.stepTowards(world, enemy.Waypoints[enemy.Waypoint%len(enemy.Waypoints)]) {
+		enemy.forgetTarget()
+	} else {
+		// Something is in the way, try the next point on the next turn
+		enemy.Waypoint = (enemy.Waypoint + 1) % len(enemy.Waypoints)
+		enemy.Turn.Movement = 0
+	}
+}
+
+// Picks open tiles around home, the patrol always passes home too
+func planPatrol(world *World, enemy *Enemy) {
+	enemy.Waypoints = []IVector2{enemy.Home}
+	enemy.Waypoint = 0
+	for tries := 0; tries < PATROL_WAYPOINTS*4 && len(enemy.Waypoints) <= PATROL_WAYPOINTS; tries++ {
+		dx := int32(world.rng.Intn(PATROL_RADIUS*2+1) - PATROL_RADIUS)
+		dy := int32(world.rng.Intn(PATROL_RADIUS*2+1) - PATROL_RADIUS)
+		pos := NewIVector2(enemy.Home.X+dx*TILE_SIZE, enemy.Home.Y+dy*TILE_SIZE)
+		if tile, ok := world.GetMapTile(pos); ok && !tile.Block && pos != enemy.Home {
+			enemy.Waypoints = append(enemy.Waypoints, pos)
+		}
+	}
+}
+
+// GuardBehaviour stands at its post and only goes after a player who comes close to it
+type GuardBehaviour struct{}
+
+func (GuardBehaviour) Attacks(world *World, enemy *Enemy) bool {
+	return true
+}
+
+func (GuardBehaviour) Move(world *World, enemy *Enemy) {
+	player := world.Player.Pos
+	if enemy.CanSeePlayer(world) && distance(enemy.Home, player) <= GUARD_LEASH {
+		enemy.LastKnownPlayerPos = player
+		if enemy.DistanceToPlayer(world) <= enemy.AttackRange() || !enemy.stepTowards(world, player) {
+			enemy.Turn.Movement = 0
+		}
+		return
+	}
+
+	enemy.forgetTarget()
+	if enemy.Pos == enemy.Home || !enemy.stepTowards(world, enemy.Home) {
+		enemy.Turn.Movement = 0
+	}
+}
+
+// FleeBehaviour chases the player until it gets hurt badly, then runs from them.
+// It still fights back when cornered.
+type FleeBehaviour struct{}
+
+func fleeing(world *World, enemy *Enemy) bool {
+	return enemy.Health < enemy.MaxHealth*FLEE_HEALTH && enemy.CanSeePlayer(world)
+}
+
+func (FleeBehaviour) Attacks(world *World, enemy *Enemy) bool {
+	if !fleeing(world, enemy) {
+		return true
+	}
+	_, canRun := enemy.awayFrom(world, world.Player.Pos)
+	return !canRun
+}
+
+func (FleeBehaviour) Move(world *World, enemy *Enemy) {
+	if !fleeing(world, enemy) {
+		ChaseBehaviour{}.Move(world, enemy)
+		return
+	}
+
+	if next, ok := enemy.awayFrom(world, world.Player.Pos); ok {
+		enemy.stepTo(world, next)
+		enemy.forgetTarget()
+	} else {
+		enemy.Turn.Movement = 0
+	}
+}
+
+// KiteBehaviour keeps its distance and attacks from range, backing off when the player closes in
+type KiteBehaviour struct{}
+
+func (KiteBehaviour) Attacks(world *World, enemy *Enemy) bool {
+	return true
+}
+
+func (KiteBehaviour) Move(world *World, enemy *Enemy) {
+	if !enemy.CanSeePlayer(world) {
+		ChaseBehaviour{}.Move(world, enemy)
+		return
+	}
+
+	enemy.LastKnownPlayerPos = world.Player.Pos
+	dist := enemy.DistanceToPlayer(world)
+	if dist < KITE_DISTANCE {
+		if next, ok := enemy.awayFrom(world, world.Player.Pos); ok {
+			enemy.stepTo(world, next)
+			return
+		}
+	}
+	if dist <= enemy.AttackRange() {
+		enemy.Turn.Movement = 0
+		return
+	}
+	ChaseBehaviour{}.Move(world, enemy)
+}
+
+// PackBehaviour chases like the rest but calls the enemies around it in when it spots the player
+type PackBehaviour struct{}
+
+func (PackBehaviour) Attacks(world *World, enemy *Enemy) bool {
+	return true
+}
+
+func (PackBehaviour) Move(world *World, enemy *Enemy) {
+	if enemy.CanSeePlayer(world) && enemy.LastKnownPlayerPos != world.Player.Pos {
+		alertPack(world, enemy)
+	}
+	ChaseBehaviour{}.Move(world, enemy)
+}
+
+// Sends the enemies near the caller to where the player is
+func alertPack(world *World, caller *Enemy) {
+	player := world.Player.Pos
+	alerted := 0
+	for _, enemy := range world.Enemies {
+		if enemy == caller || enemy.Health <= 0.0 || enemy.LastKnownPlayerPos == player {
+			continue
+		}
+		if distance(enemy.Pos, caller.Pos) <= PACK_ALERT_RANGE {
+			enemy.LastKnownPlayerPos = player
+			alerted++
+		}
+	}
+	if alerted > 0 {
+		world.emit(EVENT_ALERTED, caller.Pos, "%v at %v alerted %d others", caller.Kind, tilePoint(caller.Pos), alerted)
+	}
+}
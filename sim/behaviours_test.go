@@ -0,0 +1,187 @@
+package sim
+
+import (
+	"testing"
+)
+
+// Plays out one turn of the enemy alone and returns how many attacks it made, hits and misses alike
+func takeTurn(world *World, enemy *Enemy) int {
+	world.Player.Health = 1e6
+	enemy.StartTurn()
+	world.Events.Flush()
+	for i := 0; i < MAX_ENEMY_ACTIONS_PER_TURN && !enemy.Turn.Done; i++ {
+		enemy.DoAction(world)
+	}
+
+	attacks := 0
+	for _, event := range world.Events.Flush() {
+		if event.Kind == EVENT_PLAYER_HIT || event.Kind == EVENT_DODGED {
+			attacks++
+		}
+	}
+	return attacks
+}
+
+const behaviourTestMap = `
+@@@@@@@@@@@@@@
+@P___________@
+@____________@
+@____________@
+@@@@@@@@@@@@@@
+`
+
+func newBehaviourTestWorld(t *testing.T, behaviour string, pos IVector2) (*World, *Enemy) {
+	world := newTestWorld(t, 1, behaviourTestMap)
+	archetype := goblinArchetype
+	archetype.Behaviour = behaviour
+	enemy := archetype.NewEnemy(pos, 0)
+	world.Enemies = []*Enemy{enemy}
+	return world, enemy
+}
+
+func TestFleeBehaviour(t *testing.T) {
+	world, enemy := newBehaviourTestWorld(t, "flee", tilePos(3, 2))
+
+	// Healthy, it comes for the player
+	if takeTurn(world, enemy) == 0 {
+		t.Error("the healthy enemy didn't attack")
+	}
+
+	enemy.Pos = tilePos(3, 2)
+	enemy.Health = enemy.MaxHealth * FLEE_HEALTH / 2
+	before := enemy.DistanceToPlayer(world)
+	if takeTurn(world, enemy) != 0 {
+		t.Error("the hurt enemy attacked instead of fleeing")
+	}
+	if enemy.DistanceToPlayer(world) <= before {
+		t.Errorf("the hurt enemy didn't run, %.1f tiles away", enemy.DistanceToPlayer(world))
+	}
+}
+
+func TestFleeingEnemyFightsWhenCornered(t *testing.T) {
+	world := newTestWorld(t, 1, `
+@@@@@
+@Pg@@
+@@@@@
+`)
+	enemy := world.Enemies[0]
+	enemy.Behaviour = "flee"
+	enemy.Health = 1.0
+
+	if takeTurn(world, enemy) == 0 {
+		t.Error("the cornered enemy didn't fight back")
+	}
+}
+
+func TestGuardBehaviour(t *testing.T) {
+	world, enemy := newBehaviourTestWorld(t, "guard", tilePos(9, 2))
+
+	// The player is outside the leash, the guard stays put
+	takeTurn(world, enemy)
+	if enemy.Pos != enemy.Home {
+		t.Errorf("guard left its post for a player %.1f tiles away", distance(enemy.Home, world.Player.Pos))
+	}
+
+	// Inside the leash it goes after them, and back home once they leave
+	world.Player.Pos = tilePos(6, 2)
+	takeTurn(world, enemy)
+	if enemy.Pos == enemy.Home {
+		t.Error("guard didn't go after the player")
+	}
+	world.Player.Pos = tilePos(1, 1)
+	for i := 0; i < 3; i++ {
+		takeTurn(world, enemy)
+	}
+	if enemy.Pos != enemy.Home {
+		t.Errorf("guard at %v didn't return to %v", tilePoint(enemy.Pos), tilePoint(enemy.Home))
+	}
+}
+
+func TestKiteBehaviour(t *testing.T) {
+	world, enemy := newBehaviourTestWorld(t, "kite", tilePos(2, 2))
+	enemy.Range = 4
+
+	if takeTurn(world, enemy) == 0 {
+		t.Error("the kiting enemy didn't attack")
+	}
+	if enemy.DistanceToPlayer(world) < KITE_DISTANCE {
+		t.Errorf("the kiting enemy stayed %.1f tiles away", enemy.DistanceToPlayer(world))
+	}
+
+	// From range it keeps attacking without closing in
+	enemy.Pos = tilePos(5, 1)
+	takeTurn(world, enemy)
+	if enemy.Pos != tilePos(5, 1) {
+		t.Errorf("the kiting enemy moved to %v while in range", tilePoint(enemy.Pos))
+	}
+}
+
+func TestPatrolBehaviour(t *testing.T) {
+	world, enemy := newBehaviourTestWorld(t, "patrol", tilePos(6, 2))
+	world.Player.Pos = tilePos(1, 1)
+	world.Player.Stats.Visibility = 0
+	enemy.Stats.Visibility = 0
+
+	visited := make(map[IVector2]bool)
+	for i := 0; i < 30; i++ {
+		enemy.StartTurn()
+		for j := 0; j < MAX_ENEMY_ACTIONS_PER_TURN && !enemy.Turn.Done; j++ {
+			enemy.DoAction(world)
+			visited[enemy.Pos] = true
+		}
+	}
+	if len(enemy.Waypoints) < 2 {
+		t.Fatalf("expected a patrol route, got %v", enemy.Waypoints)
+	}
+	for _, waypoint := range enemy.Waypoints {
+		if !visited[waypoint] {
+			t.Errorf("waypoint %v was never visited", tilePoint(waypoint))
+		}
+	}
+}
+
+func TestPackBehaviour(t *testing.T) {
+	world, caller := newBehaviourTestWorld(t, "pack", tilePos(4, 1))
+	near := goblinArchetype.NewEnemy(tilePos(10, 3), 0)
+	far := goblinArchetype.NewEnemy(tilePos(12, 3), 0)
+	world.Enemies = append(world.Enemies, near, far)
+	world.Player.Pos = tilePos(1, 2)
+
+	takeTurn(world, caller)
+	if near.LastKnownPlayerPos != world.Player.Pos {
+		t.Error("the enemy in range wasn't alerted")
+	}
+	if far.LastKnownPlayerPos == world.Player.Pos {
+		t.Error("the enemy out of range was alerted")
+	}
+	alerts := 0
+	for _, event := range world.Events.Entries {
+		if event.Kind == EVENT_ALERTED {
+			alerts++
+		}
+	}
+	if alerts != 1 {
+		t.Errorf("expected one alert, got %d", alerts)
+	}
+}
+
+func TestEnemiesDontShareTiles(t *testing.T) {
+	world := newTestWorld(t, 1, `
+@@@@@@
+@P_gg@
+@@@@@@
+`)
+	for i := 0; i < 100; i++ {
+		for _, enemy := range world.Enemies {
+			enemy.Turn.Movement = 1
+			wander(world, enemy)
+		}
+		first, second := world.Enemies[0], world.Enemies[1]
+		if first.Pos == second.Pos {
+			t.Fatalf("both enemies stand on %v", tilePoint(first.Pos))
+		}
+		if first.Pos == world.Player.Pos || second.Pos == world.Player.Pos {
+			t.Fatal("an enemy wandered onto the player")
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package sim
+
+import (
+	"fov"
+)
+
+// File name of the player's sprite, enemies get theirs from their archetype
+const PLAYER_SPRITE = "player_idle.png"
+
+type TurnData struct {
+	Movement uint8
+	Actions  uint8
+	Done     bool
+	// Spent on taking turns, see TurnScheduler
+	Energy int
+}
+
+type Stats struct {
+	Movement   uint8
+	Visibility uint8
+	Vitality   uint8
+	Strength   uint8
+	Dexterity  uint8
+}
+
+type Character interface {
+	GetPos() IVector2
+	GetTurn() *TurnData
+	GetStats() *Stats
+	// Stats with the bonuses of the character's equipment and the changes of its effects
+	EffectiveStats() Stats
+	GetEffects() *Effects
+	GetSprite() string
+	StartTurn()
+}
+
+// Damage per point of Strength, for both the player and enemies
+const ATTACK_DAMAGE_MULT = 1.2
+
+// Damage a character with the given stats adds to the dice of every hit, see ResolveAttack
+func AttackDamage(stats *Stats) float32 {
+	return float32(stats.Strength) * ATTACK_DAMAGE_MULT
+}
+
+// Enemies standing diagonally next to their target can still hit it
+const MELEE_RANGE = 1.5
+
+func InVisRange(source IVector2, target IVector2, visStat uint8) bool {
+	visrange := int32(visStat) * TILE_SIZE
+	return !(target.X > source.X+(visrange) || target.X < source.X-(visrange) || target.Y > source.Y+(visrange) || target.Y < source.Y-(visrange))
+}
+
+const PLAYER_HEALTH_MULT = 5.0
+
+type Player struct {
+	Pos       IVector2
+	Health    float32
+	Stats     Stats
+	Turn      TurnData
+	Inventory Inventory
+	Progress  Progress
+	Effects   Effects
+	Abilities AbilityBar
+}
+
+func (player *Player) GetPos() IVector2 {
+	return player.Pos
+}
+
+func (player *Player) GetTurn() *TurnData {
+	return &player.Turn
+}
+
+func (player *Player) GetStats() *Stats {
+	return &player.Stats
+}
+
+func (player *Player) GetEffects() *Effects {
+	return &player.Effects
+}
+
+func (player *Player) GetSprite() string {
+	return PLAYER_SPRITE
+}
+
+func (player *Player) MaxHealth() float32 {
+	return float32(player.EffectiveStats().Vitality) * PLAYER_HEALTH_MULT
+}
+
+func (player *Player) IsDead() bool {
+	return player.Health <= 0.0
+}
+
+func (player *Player) StartTurn() {
+	player.Turn.Actions = 3
+	player.Turn.Movement = player.EffectiveStats().Movement
+	player.Turn.Done = false
+}
+
+// EndTurn ends the player's turn and hands the world over to the enemies
+func (world *World) EndTurn() {
+	world.endTurn(world.Player)
+	world.Turns.BeginEnemyPhase(world)
+	world.Player.Turn.Done = true
+}
+
+// Offset of the tile the player wants to move to, diagonals need two directions pressed together
+func moveDirection(input Input) IVector2 {
+	var dir IVector2
+
+	if input.Has(INPUT_LEFT) {
+		dir.X -= TILE_SIZE
+	}
+	if input.Has(INPUT_RIGHT) {
+		dir.X += TILE_SIZE
+	}
+	if input.Has(INPUT_UP) {
+		dir.Y -= TILE_SIZE
+	}
+	if input.Has(INPUT_DOWN) {
+		dir.Y += TILE_SIZE
+	}
+
+	return dir
+}
+
+type Enemy struct {
+	// Name of the archetype the enemy was made from
+	Kind      string
+	Sprite    string
+	Behaviour string
+	Pos       IVector2
+	Health    float32
+	MaxHealth float32
+	// Attack range in tiles, melee if below MELEE_RANGE
+	Range              float32
+	LightLevel         uint8 `json:"-"`
+	LastKnownPlayerPos IVector2
+	// Where the enemy spawned, guards stay close to it and patrols start from it
+	Home      IVector2
+	Waypoints []IVector2
+	Waypoint  int
+	Stats     Stats
+	// Always equipped, the enemy drops nothing
+	Gear    []*Item
+	Effects Effects
+	Turn    TurnData
+	Sight   fov.Set    `json:"-"`
+	Path    []IVector2 `json:"-"`
+
+	// Where Sight was computed from, it is only computed again once one of these changes
+	sightPos      IVector2
+	sightRange    uint8
+	sightRevision int
+}
+
+func (enemy *Enemy) GetPos() IVector2 {
+	return enemy.Pos
+}
+
+func (enemy *Enemy) GetTurn() *TurnData {
+	return &enemy.Turn
+}
+
+func (enemy *Enemy) GetStats() *Stats {
+	return &enemy.Stats
+}
+
+func (enemy *Enemy) GetEffects() *Effects {
+	return &enemy.Effects
+}
+
+func (enemy *Enemy) GetSprite() string {
+	return enemy.Sprite
+}
+
+func (enemy *Enemy) StartTurn() {
+	enemy.Turn.Actions = 1
+	enemy.Turn.Movement = enemy.EffectiveStats().Movement
+	enemy.Turn.Done = false
+}
+
+func (enemy *Enemy) DoAction(world *World) {
+	behaviour := enemy.behaviour()
+	if enemy.Turn.Actions > 0 && enemy.CanAttackPlayer(world) && behaviour.Attacks(world, enemy) {
+		enemy.Attack(world)
+	} else if enemy.Turn.Movement > 0 {
+		behaviour.Move(world, enemy)
+	} else {
+		enemy.Turn.Done = true
+	}
+}
+
+// Enemies with a ranged attack hit from further away, everyone else from next to the player
+func (enemy *Enemy) AttackRange() float32 {
+	if enemy.Range > MELEE_RANGE {
+		return enemy.Range
+	}
+	return MELEE_RANGE
+}
+
+func (enemy *Enemy) CanAttackPlayer(world *World) bool {
+	return !world.Player.IsDead() && enemy.DistanceToPlayer(world) <= enemy.AttackRange() && enemy.CanSeePlayer(world)
+}
+
+func (enemy *Enemy) Attack(world *World) {
+	attacker, defender := enemy.Combatant(), world.Player.Combatant()
+	result := ResolveAttack(&attacker, &defender, world.rng)
+	enemy.Turn.Actions--
+	if !result.Hit {
+		world.emit(EVENT_DODGED, enemy.Pos, "Enemy %v missed (%v)", enemy.Kind, result)
+		return
+	}
+
+	world.Player.Health -= result.Damage
+	verb := "hit"
+	if result.Critical {
+		verb = "critically hit"
+	}
+	world.emit(EVENT_PLAYER_HIT, enemy.Pos, "Enemy %v %v for %.1f (%v), leaving %.1f health", enemy.Kind, verb, result.Damage, result, world.Player.Health)
+	world.inflict(&attacker, world.Player, world.rng)
+	if world.Player.IsDead() {
+		world.emit(EVENT_PLAYER_DIED, world.Player.Pos, "Player died on floor %d", world.Dungeon.Depth+1)
+	}
+}
+
+func (enemy *Enemy) VisibleToPlayer(world *World) bool {
+	return world.PlayerSight[tilePoint(enemy.Pos)]
+}
+
+func (enemy *Enemy) DistanceToPlayer(world *World) float32 {
+	return distance(enemy.Pos, world.Player.Pos)
+}
+
+func (enemy *Enemy) CanSeePlayer(world *World) bool {
+	if !InVisRange(enemy.Pos, world.Player.Pos, enemy.EffectiveStats().Visibility) {
+		return false
+	}
+	return enemy.sight(world)[tilePoint(world.Player.Pos)]
+}
+
+// sight updates Sight if the enemy has moved, its visibility has changed or tiles have been dug since it was computed
+func (enemy *Enemy) sight(world *World) fov.Set {
+	visibility := enemy.EffectiveStats().Visibility
+	if enemy.Sight == nil || enemy.sightPos != enemy.Pos || enemy.sightRange != visibility || enemy.sightRevision != world.tileRevision {
+		enemy.Sight = world.ComputeFOV(enemy.Pos, visibility)
+		enemy.sightPos = enemy.Pos
+		enemy.sightRange = visibility
+		enemy.sightRevision = world.tileRevision
+	}
+	return enemy.Sight
+}
+
+func (enemy *Enemy) LightEmittedToTile(tile *Tile) uint8 {
+	if !enemy.Sight[tilePoint(tile.Pos)] {
+		return 0
+	}
+	distance := tile.DistanceToEnemy(enemy)
+	return calculateLightLevel(distance, enemy.EffectiveStats().Visibility)
+}
+
+func DefaultEnemyTurn() TurnData {
+	return TurnData{
+		Movement: 0,
+		Actions:  0,
+		Done:     true,
+		Energy:   TURN_ENERGY,
+	}
+}
@@ -0,0 +1,152 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Attacks hit when a d20 plus the attacker's Dexterity reaches the defender's evasion,
+// EVASION_BASE plus their Dexterity
+const HIT_DIE = 20
+const EVASION_BASE = 8
+
+// A natural 20 always hits and multiplies the damage before armour, a natural 1 always misses
+const CRITICAL_MULT = 2.0
+
+// Hits that get through the armour always do at least this much
+const MIN_DAMAGE = 1.0
+
+// Rolled by characters without a weapon
+var UNARMED_DAMAGE = Dice{Count: 1, Sides: 4}
+
+// Combatant is everything about a character that goes into resolving an attack
+type Combatant struct {
+	Stats  Stats
+	Damage []Dice
+	Armour float32
+	// Rolled for on every hit
+	Inflicts []Infliction
+	Effects  Effects
+}
+
+func newCombatant(character Character, gear []*Item) Combatant {
+	damage := gearDamage(gear)
+	if equippedTo(gear, ITEM_WEAPON) == nil {
+		damage = append([]Dice{UNARMED_DAMAGE}, damage...)
+	}
+	return Combatant{
+		Stats:    character.EffectiveStats(),
+		Damage:   damage,
+		Armour:   gearArmour(gear),
+		Inflicts: gearInflictions(gear),
+		Effects:  *character.GetEffects(),
+	}
+}
+
+func (player *Player) Combatant() Combatant {
+	return newCombatant(player, player.Inventory.Items)
+}
+
+func (enemy *Enemy) Combatant() Combatant {
+	return newCombatant(enemy, enemy.Gear)
+}
+
+func (combatant *Combatant) Evasion() int {
+	return EVASION_BASE + int(combatant.Stats.Dexterity)
+}
+
+// AttackResult holds every roll of an attack, so it can be shown in the combat log
+type AttackResult struct {
+	// The natural d20 and the attacker's Dexterity added to it
+	Roll     int
+	Bonus    int
+	Evasion  int
+	Hit      bool
+	Critical bool
+	// Sum of the damage dice and the damage from Strength on top of them
+	DamageRoll     int
+	StrengthDamage float32
+	// Taken off by the defender's armour
+	Absorbed float32
+	Damage   float32
+}
+
+// ResolveAttack rolls an attack, the damage dice are only rolled when it hits
+func ResolveAttack(attacker *Combatant, defender *Combatant, rng *rand.Rand) AttackResult {
+	result := AttackResult{
+		Roll:    rng.Intn(HIT_DIE) + 1,
+		Bonus:   int(attacker.Stats.Dexterity),
+		Evasion: defender.Evasion(),
+	}
+	switch result.Roll {
+	case 1:
+		return result
+	case HIT_DIE:
+		result.Critical = true
+	}
+	result.Hit = result.Critical || result.Roll+result.Bonus >= result.Evasion
+	if !result.Hit {
+		return result
+	}
+
+	for _, dice := range attacker.Damage {
+		result.DamageRoll += dice.Roll(rng)
+	}
+	result.StrengthDamage = AttackDamage(&attacker.Stats)
+	damage := float32(result.DamageRoll) + result.StrengthDamage
+	if result.Critical {
+		damage *= CRITICAL_MULT
+	}
+	damage = defender.Effects.damaged(damage)
+
+	result.Absorbed = defender.Armour
+	if damage-result.Absorbed < MIN_DAMAGE {
+		result.Absorbed = damage - MIN_DAMAGE
+		if result.Absorbed < 0.0 {
+			result.Absorbed = 0.0
+		}
+	}
+	result.Damage = damage - result.Absorbed
+	return result
+}
+
+func (result AttackResult) String() string {
+	s := fmt.Sprintf("d20 %d+%d vs %d", result.Roll, result.Bonus, result.Evasion)
+	if !result.Hit {
+		return s
+	}
+
+	damage := fmt.Sprintf("%d+%.1f", result.DamageRoll, result.StrengthDamage)
+	if result.Critical {
+		damage = fmt.Sprintf("(%v)x%v", damage, CRITICAL_MULT)
+	}
+	s += ", damage " + damage
+	if result.Absorbed > 0.0 {
+		s += fmt.Sprintf(", %.1f absorbed", result.Absorbed)
+	}
+	return s
+}
+
+// strike resolves an attack of the player on the enemy and reports it,
+// effects are inflicted on a hit and a kill is worth experience
+func (world *World) strike(attacker *Combatant, enemy *Enemy) AttackResult {
+	defender := enemy.Combatant()
+	result := ResolveAttack(attacker, &defender, world.rng)
+	if !result.Hit {
+		world.emit(EVENT_MISSED, enemy.Pos, "Missed %v (%v)", enemy.Kind, result)
+		return result
+	}
+
+	enemy.Health -= result.Damage
+	verb := "Hit"
+	if result.Critical {
+		verb = "Critically hit"
+	}
+	world.emit(EVENT_ATTACKED, enemy.Pos, "%v %v for %.1f (%v), leaving %.1f health", verb, enemy.Kind, result.Damage, result, enemy.Health)
+	world.inflict(attacker, enemy, world.rng)
+	if enemy.Health <= 0.0 {
+		world.emit(EVENT_KILLED, enemy.Pos, "Killed %v at %v for %d experience", enemy.Kind, tilePoint(enemy.Pos), ExperienceFor(enemy))
+		world.awardExperience(enemy)
+	}
+	return result
+}
@@ -0,0 +1,145 @@
+package sim
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Reseeds the world's random numbers so the next attack rolls a natural 20 and can't miss
+func rollCritical(world *World) {
+	for seed := int64(1); ; seed++ {
+		if rand.New(NewRandomSource(seed, 0)).Intn(HIT_DIE)+1 == HIT_DIE {
+			world.restoreRng(seed, 0)
+			return
+		}
+	}
+}
+
+func TestResolveAttackIsDeterministic(t *testing.T) {
+	attacker := Combatant{Stats: Stats{Strength: 3, Dexterity: 4}, Damage: []Dice{{Count: 2, Sides: 6}}}
+	defender := Combatant{Stats: Stats{Dexterity: 5}, Armour: 1}
+
+	first := rand.New(rand.NewSource(7))
+	second := rand.New(rand.NewSource(7))
+	for i := 0; i < 100; i++ {
+		a, b := ResolveAttack(&attacker, &defender, first), ResolveAttack(&attacker, &defender, second)
+		if a != b {
+			t.Fatalf("attack %d came out as %v and %v", i, a, b)
+		}
+	}
+}
+
+func TestNaturalRolls(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	// The attacker can't miss and the defender can't be hit, other than on natural rolls
+	sure := Combatant{Stats: Stats{Dexterity: 200}}
+	evasive := Combatant{Stats: Stats{Dexterity: 200}}
+	weak := Combatant{}
+
+	var fumbles, criticals int
+	for i := 0; i < 1000; i++ {
+		if result := ResolveAttack(&sure, &weak, rng); result.Roll == 1 {
+			fumbles++
+			if result.Hit || result.Damage != 0 {
+				t.Fatalf("a natural 1 hit: %v", result)
+			}
+		} else if !result.Hit {
+			t.Fatalf("missed with %v", result)
+		}
+
+		if result := ResolveAttack(&weak, &evasive, rng); result.Roll == HIT_DIE {
+			criticals++
+			if !result.Hit || !result.Critical {
+				t.Fatalf("a natural 20 wasn't a critical hit: %v", result)
+			}
+		} else if result.Hit {
+			t.Fatalf("hit with %v", result)
+		}
+	}
+	if fumbles == 0 || criticals == 0 {
+		t.Errorf("got %d natural 1s and %d natural 20s in 1000 rolls", fumbles, criticals)
+	}
+}
+
+func TestAttackDamage(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	attacker := Combatant{Stats: Stats{Strength: 1, Dexterity: 50}, Damage: []Dice{{Count: 1, Sides: 6}}}
+	defender := Combatant{}
+	strength := AttackDamage(&attacker.Stats)
+
+	for i := 0; i < 100; i++ {
+		result := ResolveAttack(&attacker, &defender, rng)
+		if !result.Hit {
+			continue
+		}
+		min, max := strength+1, strength+6
+		if result.Critical {
+			min, max = min*CRITICAL_MULT, max*CRITICAL_MULT
+		}
+		if result.Damage < min || result.Damage > max {
+			t.Fatalf("%v did %v damage, expected %v to %v", result, result.Damage, min, max)
+		}
+	}
+
+	// Armour takes off damage, but never all of it
+	defender.Armour = 100
+	for i := 0; i < 100; i++ {
+		result := ResolveAttack(&attacker, &defender, rng)
+		if result.Hit && (result.Damage != MIN_DAMAGE || result.Absorbed <= 0) {
+			t.Fatalf("armour didn't absorb the hit: %v did %v damage", result, result.Damage)
+		}
+	}
+}
+
+func TestDexterityHitsMoreOften(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	defender := Combatant{Stats: Stats{Dexterity: 5}}
+	hitRate := func(dexterity uint8) int {
+		attacker := Combatant{Stats: Stats{Dexterity: dexterity}}
+		hits := 0
+		for i := 0; i < 1000; i++ {
+			if ResolveAttack(&attacker, &defender, rng).Hit {
+				hits++
+			}
+		}
+		return hits
+	}
+
+	if clumsy, nimble := hitRate(0), hitRate(10); clumsy >= nimble {
+		t.Errorf("%d hits with 0 Dexterity, %d with 10", clumsy, nimble)
+	}
+}
+
+func TestUnarmedDamage(t *testing.T) {
+	player := NewPlayer()
+	if combatant := player.Combatant(); len(combatant.Damage) != 1 || combatant.Damage[0] != UNARMED_DAMAGE {
+		t.Errorf("unarmed player rolls %v", combatant.Damage)
+	}
+
+	player.Inventory.Items = []*Item{{Name: "axe", Category: ITEM_WEAPON, Equipped: true, Damage: Dice{Count: 1, Sides: 8}}}
+	if combatant := player.Combatant(); len(combatant.Damage) != 1 || combatant.Damage[0] != player.Inventory.Items[0].Damage {
+		t.Errorf("armed player rolls %v", combatant.Damage)
+	}
+}
+
+func TestUndoAttackKeepsRolls(t *testing.T) {
+	world := newCommandTestWorld(t)
+	world.Player.Pos = tilePos(2, 2)
+	enemy := world.enemyAt(tilePos(3, 2))
+	enemy.Health = 1000.0
+
+	for i := 0; i < 5; i++ {
+		first := NewAttackCommand(world, enemy.Pos)
+		if err := world.Execute(first); err != nil {
+			t.Fatal(err)
+		}
+		world.Commands.Undo(world)
+
+		again := NewAttackCommand(world, enemy.Pos)
+		world.Execute(again)
+		if first.Result != again.Result {
+			t.Fatalf("attack rerolled after undo: %v, then %v", first.Result, again.Result)
+		}
+		world.Player.Turn.Actions++
+	}
+}
@@ -0,0 +1,598 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Tiles and enemies further away than this can't be dug or attacked by the player
+const PLAYER_REACH = MELEE_RANGE
+
+// Command is a single action taken by the player.
+// Commands are run through CommandLog.Execute, which validates them first
+// and keeps them around so that they can be undone until the turn ends.
+type Command interface {
+	Validate(world *World) error
+	Execute(world *World)
+	Undo(world *World)
+	String() string
+}
+
+var errTurnOver = errors.New("the turn is already over")
+
+// finalCommand is a command that can turn out not to be undoable once executed,
+// it then makes everything taken before it permanent too
+type finalCommand interface {
+	final() bool
+}
+
+func isFinal(command Command) bool {
+	f, ok := command.(finalCommand)
+	return ok && f.final()
+}
+
+// CommandLog runs the player's commands and remembers the ones taken during the current turn
+type CommandLog struct {
+	History []Command
+}
+
+func (commands *CommandLog) Execute(world *World, command Command) error {
+	if err := command.Validate(world); err != nil {
+		debugPrint(fmt.Sprintf("Rejected %v: %v", command, err))
+		return err
+	}
+
+	command.Execute(world)
+	debugPrint(fmt.Sprintf("Executed %v", command))
+
+	if _, ok := command.(*EndTurnCommand); ok || isFinal(command) {
+		commands.Commit()
+	} else {
+		commands.History = append(commands.History, command)
+	}
+	return nil
+}
+
+// Undo takes back the latest command of the current turn
+func (commands *CommandLog) Undo(world *World) bool {
+	length := len(commands.History)
+	if length == 0 || world.Player.Turn.Done {
+		return false
+	}
+
+	command := commands.History[length-1]
+	commands.History = commands.History[:length-1]
+	command.Undo(world)
+	world.emit(EVENT_UNDONE, world.Player.Pos, "Undid %v", command)
+	return true
+}
+
+// Commit makes the commands taken so far permanent
+func (commands *CommandLog) Commit() {
+	commands.History = nil
+}
+
+// Executes a command with the world's command log
+func (world *World) Execute(command Command) error {
+	return world.Commands.Execute(world, command)
+}
+
+func (world *World) withinReach(pos IVector2) bool {
+	return distance(world.Player.Pos, pos) <= PLAYER_REACH
+}
+
+// Dead enemies are cleared out every frame, reviveEnemy brings one back if that already happened
+func (world *World) reviveEnemy(enemy *Enemy) {
+	for _, e := range world.Enemies {
+		if e == enemy {
+			return
+		}
+	}
+	world.Enemies = append(world.Enemies, enemy)
+	world.Kills--
+}
+
+func (world *World) enemyAt(pos IVector2) *Enemy {
+	for _, enemy := range world.Enemies {
+		if enemy.Pos == pos && enemy.Health > 0.0 {
+			return enemy
+		}
+	}
+	return nil
+}
+
+type MoveCommand struct {
+	From IVector2
+	To   IVector2
+	// Bleeding on the step can't be taken back, it may even have killed the player
+	hurt bool
+}
+
+func NewMoveCommand(world *World, to IVector2) *MoveCommand {
+	return &MoveCommand{From: world.Player.Pos, To: to}
+}
+
+func (command *MoveCommand) Validate(world *World) error {
+	turn := &world.Player.Turn
+	if turn.Done {
+		return errTurnOver
+	}
+	if turn.Movement == 0 {
+		return errors.New("no movement left")
+	}
+	if command.From != world.Player.Pos {
+		return errors.New("the player has moved")
+	}
+
+	dx := command.To.X - command.From.X
+	dy := command.To.Y - command.From.Y
+	if (dx == 0 && dy == 0) || abs(int(dx)) > int(TILE_SIZE) || abs(int(dy)) > int(TILE_SIZE) {
+		return errors.New("not a neighbouring tile")
+	}
+
+	tile, ok := world.GetMapTile(IVector2{X: command.To.X - PLAYER_OFFSET_X, Y: command.To.Y - PLAYER_OFFSET_Y})
+	if !ok || tile.Block {
+		return errors.New("the way is blocked")
+	}
+	if world.enemyAt(command.To) != nil {
+		return errors.New("an enemy is in the way")
+	}
+	return nil
+}
+
+func (command *MoveCommand) Execute(world *World) {
+	world.Player.Pos = command.To
+	world.Player.Turn.Movement--
+	world.emit(EVENT_MOVED, command.To, "Moved to %v", tilePoint(command.To))
+	health := world.Player.Health
+	world.moved(world.Player)
+	command.hurt = world.Player.Health < health
+}
+
+func (command *MoveCommand) final() bool {
+	return command.hurt
+}
+
+func (command *MoveCommand) Undo(world *World) {
+	world.Player.Pos = command.From
+	world.Player.Turn.Movement++
+}
+
+func (command *MoveCommand) String() string {
+	return fmt.Sprintf("move %v -> %v", tilePoint(command.From), tilePoint(command.To))
+}
+
+// Digging takes an action, or a point of movement when the player carries a digging tool
+type DigCommand struct {
+	Pos      IVector2
+	before   Tile
+	withTool bool
+}
+
+func NewDigCommand(pos IVector2) *DigCommand {
+	return &DigCommand{Pos: pos}
+}
+
+func (command *DigCommand) Validate(world *World) error {
+	turn := &world.Player.Turn
+	if turn.Done {
+		return errTurnOver
+	}
+	if turn.Actions == 0 && !world.canDigWithTool() {
+		return errors.New("no actions left")
+	}
+
+	tile, ok := world.GetMapTile(command.Pos)
+	if !ok || !tile.Block {
+		return errors.New("nothing to dig")
+	}
+	if !world.withinReach(command.Pos) {
+		return errors.New("out of reach")
+	}
+	return nil
+}
+
+func (command *DigCommand) Execute(world *World) {
+	tile, _ := world.GetMapTile(command.Pos)
+	command.before = *tile
+	tile.Destroy()
+	world.tileRevision++
+	command.withTool = world.canDigWithTool()
+	if command.withTool {
+		world.Player.Turn.Movement--
+	} else {
+		world.Player.Turn.Actions--
+	}
+	world.emit(EVENT_DUG, command.Pos, "Dug through %v", tilePoint(command.Pos))
+}
+
+func (command *DigCommand) Undo(world *World) {
+	if tile, ok := world.GetMapTile(command.Pos); ok {
+		*tile = command.before
+		world.tileRevision++
+	}
+	if command.withTool {
+		world.Player.Turn.Movement++
+	} else {
+		world.Player.Turn.Actions++
+	}
+}
+
+func (command *DigCommand) String() string {
+	return fmt.Sprintf("dig %v", tilePoint(command.Pos))
+}
+
+func (world *World) canDigWithTool() bool {
+	return world.Player.Turn.Movement > 0 && world.Player.Inventory.Has(ITEM_DIGGING_TOOL)
+}
+
+type AttackCommand struct {
+	Target *Enemy
+	Result AttackResult
+	health float32
+	// Kills are worth experience, taken back on undo
+	progress Progress
+	effects  Effects
+	// Undoing puts the random numbers back too, so an attack can't be taken back and rerolled
+	rngSeed  int64
+	rngCalls uint64
+}
+
+// NewAttackCommand targets the enemy standing on pos, if there is one
+func NewAttackCommand(world *World, pos IVector2) *AttackCommand {
+	return &AttackCommand{Target: world.enemyAt(pos)}
+}
+
+func (command *AttackCommand) Validate(world *World) error {
+	turn := &world.Player.Turn
+	if turn.Done {
+		return errTurnOver
+	}
+	if turn.Actions == 0 {
+		return errors.New("no actions left")
+	}
+	if command.Target == nil || command.Target.Health <= 0.0 {
+		return errors.New("nothing to attack")
+	}
+	if !world.withinReach(command.Target.Pos) {
+		return errors.New("out of reach")
+	}
+	return nil
+}
+
+func (command *AttackCommand) Execute(world *World) {
+	enemy := command.Target
+	command.health = enemy.Health
+	command.progress = world.Player.Progress
+	command.effects = enemy.Effects.copy()
+	command.rngSeed, command.rngCalls = world.rngSource.State()
+	attacker := world.Player.Combatant()
+	world.Player.Turn.Actions--
+	command.Result = world.strike(&attacker, enemy)
+}
+
+func (command *AttackCommand) Undo(world *World) {
+	enemy := command.Target
+	enemy.Health = command.health
+	enemy.Effects = command.effects
+	world.Player.Turn.Actions++
+	world.restoreRng(command.rngSeed, command.rngCalls)
+	world.Player.restoreProgress(command.progress)
+	world.reviveEnemy(enemy)
+}
+
+func (command *AttackCommand) String() string {
+	if command.Target == nil {
+		return "attack nothing"
+	}
+	return fmt.Sprintf("attack %v", tilePoint(command.Target.Pos))
+}
+
+// Items are picked up from under the player, the one dropped last first
+type PickUpCommand struct {
+	Pos   IVector2
+	item  *FloorItem
+	index int
+}
+
+func NewPickUpCommand(world *World) *PickUpCommand {
+	return &PickUpCommand{Pos: world.Player.Pos}
+}
+
+func (command *PickUpCommand) Validate(world *World) error {
+	turn := &world.Player.Turn
+	if turn.Done {
+		return errTurnOver
+	}
+	if turn.Actions == 0 {
+		return errors.New("no actions left")
+	}
+	if command.Pos != world.Player.Pos {
+		return errors.New("the player has moved")
+	}
+
+	index, ok := world.itemAt(command.Pos)
+	if !ok {
+		return errors.New("nothing to pick up")
+	}
+	return world.Player.canCarry(world.Items[index].Item)
+}
+
+func (command *PickUpCommand) Execute(world *World) {
+	command.index, _ = world.itemAt(command.Pos)
+	command.item = world.Items[command.index]
+	world.Items = append(world.Items[:command.index], world.Items[command.index+1:]...)
+
+	inventory := &world.Player.Inventory
+	inventory.Items = append(inventory.Items, command.item.Item)
+	inventory.Selected = len(inventory.Items) - 1
+	world.Player.Turn.Actions--
+	world.emit(EVENT_PICKED_UP, command.Pos, "Picked up %v", command.item.Item.Name)
+}
+
+func (command *PickUpCommand) Undo(world *World) {
+	inventory := &world.Player.Inventory
+	inventory.remove(len(inventory.Items) - 1)
+
+	world.Items = append(world.Items, nil)
+	copy(world.Items[command.index+1:], world.Items[command.index:])
+	world.Items[command.index] = command.item
+	world.Player.Turn.Actions++
+}
+
+func (command *PickUpCommand) String() string {
+	if command.item == nil {
+		return fmt.Sprintf("pick up at %v", tilePoint(command.Pos))
+	}
+	return fmt.Sprintf("pick up %v at %v", command.item.Item.Name, tilePoint(command.Pos))
+}
+
+// Drops the selected item where the player stands, taking it off first if it is equipped
+type DropCommand struct {
+	Index    int
+	item     *Item
+	equipped bool
+	health   float32
+}
+
+func NewDropCommand(world *World) *DropCommand {
+	return &DropCommand{Index: world.Player.Inventory.Selected}
+}
+
+func (command *DropCommand) Validate(world *World) error {
+	turn := &world.Player.Turn
+	if turn.Done {
+		return errTurnOver
+	}
+	if turn.Actions == 0 {
+		return errors.New("no actions left")
+	}
+	if command.Index < 0 || command.Index >= len(world.Player.Inventory.Items) {
+		return errors.New("nothing to drop")
+	}
+	return nil
+}
+
+func (command *DropCommand) Execute(world *World) {
+	command.item = world.Player.Inventory.remove(command.Index)
+	command.equipped = command.item.Equipped
+	command.health = world.Player.Health
+	if command.equipped {
+		world.Player.unequip(command.item)
+	}
+	world.Items = append(world.Items, &FloorItem{Pos: world.Player.Pos, Item: command.item})
+	world.Player.Turn.Actions--
+	world.emit(EVENT_DROPPED, world.Player.Pos, "Dropped %v", command.item.Name)
+}
+
+func (command *DropCommand) Undo(world *World) {
+	world.Items = world.Items[:len(world.Items)-1]
+	command.item.Equipped = command.equipped
+	world.Player.Inventory.insert(command.Index, command.item)
+	world.Player.Health = command.health
+	world.Player.Turn.Actions++
+}
+
+func (command *DropCommand) String() string {
+	if command.item == nil {
+		return fmt.Sprintf("drop item %d", command.Index+1)
+	}
+	return fmt.Sprintf("drop %v", command.item.Name)
+}
+
+// Uses up the selected item, only consumables can be used
+type UseItemCommand struct {
+	Index   int
+	item    *Item
+	health  float32
+	effects Effects
+}
+
+func NewUseItemCommand(world *World) *UseItemCommand {
+	return &UseItemCommand{Index: world.Player.Inventory.Selected}
+}
+
+func (command *UseItemCommand) Validate(world *World) error {
+	turn := &world.Player.Turn
+	if turn.Done {
+		return errTurnOver
+	}
+	if turn.Actions == 0 {
+		return errors.New("no actions left")
+	}
+	items := world.Player.Inventory.Items
+	if command.Index < 0 || command.Index >= len(items) {
+		return errors.New("nothing to use")
+	}
+	if items[command.Index].Category != ITEM_CONSUMABLE {
+		return fmt.Errorf("%v can't be used", items[command.Index].Name)
+	}
+	return nil
+}
+
+// Consumables heal and put their effects on the player
+
+func (command *UseItemCommand) Execute(world *World) {
+	player := world.Player
+	command.item = player.Inventory.remove(command.Index)
+	command.health = player.Health
+	command.effects = player.Effects.copy()
+	player.Health += command.item.Heal
+	if player.Health > player.MaxHealth() {
+		player.Health = player.MaxHealth()
+	}
+	player.Turn.Actions--
+	world.emit(EVENT_USED_ITEM, player.Pos, "Used %v, health %.2f", command.item.Name, player.Health)
+	for _, infliction := range command.item.Effects {
+		world.affect(player, infliction.Effect, infliction.Turns)
+	}
+}
+
+func (command *UseItemCommand) Undo(world *World) {
+	world.Player.Health = command.health
+	world.Player.Effects = command.effects
+	world.Player.Inventory.insert(command.Index, command.item)
+	world.Player.Turn.Actions++
+}
+
+func (command *UseItemCommand) String() string {
+	if command.item == nil {
+		return fmt.Sprintf("use item %d", command.Index+1)
+	}
+	return fmt.Sprintf("use %v", command.item.Name)
+}
+
+// Equips the selected item, or takes it off if it is already equipped.
+// Whatever was in the slot before goes back into the inventory.
+type EquipCommand struct {
+	Index    int
+	item     *Item
+	previous *Item
+	health   float32
+}
+
+func NewEquipCommand(world *World) *EquipCommand {
+	return &EquipCommand{Index: world.Player.Inventory.Selected}
+}
+
+func (command *EquipCommand) Validate(world *World) error {
+	turn := &world.Player.Turn
+	if turn.Done {
+		return errTurnOver
+	}
+	if turn.Actions == 0 {
+		return errors.New("no actions left")
+	}
+	items := world.Player.Inventory.Items
+	if command.Index < 0 || command.Index >= len(items) {
+		return errors.New("nothing to equip")
+	}
+	if !items[command.Index].Equippable() {
+		return fmt.Errorf("%v can't be equipped", items[command.Index].Name)
+	}
+	return nil
+}
+
+func (command *EquipCommand) Execute(world *World) {
+	player := world.Player
+	command.item = player.Inventory.Items[command.Index]
+	command.health = player.Health
+	if command.item.Equipped {
+		player.unequip(command.item)
+		world.emit(EVENT_EQUIPPED, player.Pos, "Took off %v", command.item.Name)
+	} else {
+		command.previous = player.equip(command.item)
+		world.emit(EVENT_EQUIPPED, player.Pos, "Equipped %v", command.item.Name)
+	}
+	player.Turn.Actions--
+}
+
+func (command *EquipCommand) Undo(world *World) {
+	command.item.Equipped = !command.item.Equipped
+	if command.previous != nil {
+		command.previous.Equipped = true
+	}
+	world.Player.Health = command.health
+	world.Player.Turn.Actions++
+}
+
+func (command *EquipCommand) String() string {
+	if command.item == nil {
+		return fmt.Sprintf("equip item %d", command.Index+1)
+	}
+	return fmt.Sprintf("equip %v", command.item.Name)
+}
+
+// Spends a stat point on the stat selected in the character panel, it costs nothing else
+type RaiseStatCommand struct {
+	Stat   int
+	health float32
+}
+
+func NewRaiseStatCommand(world *World) *RaiseStatCommand {
+	return &RaiseStatCommand{Stat: world.Player.Progress.Selected}
+}
+
+func (command *RaiseStatCommand) Validate(world *World) error {
+	if world.Player.Turn.Done {
+		return errTurnOver
+	}
+	if world.Player.Progress.Points == 0 {
+		return errors.New("no stat points to spend")
+	}
+	stat := world.Player.Stats.levelled(command.Stat)
+	if stat == nil {
+		return fmt.Errorf("no stat %d to raise", command.Stat)
+	}
+	if *stat >= MAX_LEVELLED_STAT {
+		return fmt.Errorf("%v can't be raised any further", LevelledStats[command.Stat])
+	}
+	return nil
+}
+
+// Raising vitality heals by as much as the max health goes up
+func (command *RaiseStatCommand) Execute(world *World) {
+	player := world.Player
+	command.health = player.Health
+	max := player.MaxHealth()
+	stat := player.Stats.levelled(command.Stat)
+	*stat++
+	player.Progress.Points--
+	player.Health += player.MaxHealth() - max
+	world.emit(EVENT_RAISED_STAT, player.Pos, "Raised %v to %d", LevelledStats[command.Stat], *stat)
+}
+
+func (command *RaiseStatCommand) Undo(world *World) {
+	player := world.Player
+	*player.Stats.levelled(command.Stat)--
+	player.Progress.Points++
+	player.Health = command.health
+}
+
+func (command *RaiseStatCommand) String() string {
+	if command.Stat < 0 || command.Stat >= len(LevelledStats) {
+		return fmt.Sprintf("raise stat %d", command.Stat)
+	}
+	return "raise " + LevelledStats[command.Stat]
+}
+
+type EndTurnCommand struct{}
+
+func (command *EndTurnCommand) Validate(world *World) error {
+	if world.Player.Turn.Done {
+		return errTurnOver
+	}
+	return nil
+}
+
+func (command *EndTurnCommand) Execute(world *World) {
+	world.EndTurn()
+	world.emit(EVENT_TURN_ENDED, world.Player.Pos, "Ended turn %d", world.Turns.Round+1)
+}
+
+// The turn can't be taken back once the enemies have started moving
+func (command *EndTurnCommand) Undo(world *World) {}
+
+func (command *EndTurnCommand) String() string {
+	return "end turn"
+}
@@ -0,0 +1,170 @@
+package sim
+
+import (
+	"testing"
+)
+
+const commandTestMap = `
+@@@@@@
+@P_@g@
+@__g_@
+@@@@@@
+`
+
+func tilePos(x, y int32) IVector2 {
+	return NewIVector2(x*TILE_SIZE, y*TILE_SIZE)
+}
+
+func newCommandTestWorld(t *testing.T) *World {
+	world := newTestWorld(t, 1, commandTestMap)
+	world.Player.StartTurn()
+	return world
+}
+
+func TestMoveCommandValidation(t *testing.T) {
+	world := newCommandTestWorld(t)
+
+	cases := []struct {
+		name  string
+		to    IVector2
+		valid bool
+	}{
+		{"floor", tilePos(2, 1), true},
+		{"diagonal", tilePos(2, 2), true},
+		{"wall", tilePos(0, 1), false},
+		{"standing still", tilePos(1, 1), false},
+		{"too far", tilePos(3, 2), false},
+	}
+	for _, c := range cases {
+		err := NewMoveCommand(world, c.to).Validate(world)
+		if (err == nil) != c.valid {
+			t.Errorf("%s: expected valid %v, got error %v", c.name, c.valid, err)
+		}
+	}
+
+	world.Player.Pos = tilePos(2, 2)
+	if err := NewMoveCommand(world, tilePos(3, 2)).Validate(world); err == nil {
+		t.Error("moved onto an enemy")
+	}
+
+	world.Player.Turn.Movement = 0
+	if err := NewMoveCommand(world, tilePos(2, 1)).Validate(world); err == nil {
+		t.Error("moved without movement left")
+	}
+}
+
+func TestUndoRestoresTheTurn(t *testing.T) {
+	world := newCommandTestWorld(t)
+	start := world.Player.Pos
+	turn := world.Player.Turn
+	wall, _ := world.GetMapTile(tilePos(3, 1))
+	enemy := world.enemyAt(tilePos(3, 2))
+	health := enemy.Health
+
+	commands := []Command{
+		NewMoveCommand(world, tilePos(2, 1)),
+		NewDigCommand(tilePos(3, 1)),
+		NewAttackCommand(world, tilePos(3, 2)),
+	}
+	rollCritical(world)
+	for _, command := range commands {
+		if err := world.Execute(command); err != nil {
+			t.Fatalf("%v failed: %v", command, err)
+		}
+	}
+	if wall.Block || enemy.Health == health || world.Player.Pos == start {
+		t.Fatal("the commands didn't change anything")
+	}
+
+	for range commands {
+		if !world.Commands.Undo(world) {
+			t.Fatal("nothing to undo")
+		}
+	}
+	if world.Commands.Undo(world) {
+		t.Error("undid more commands than were executed")
+	}
+
+	if world.Player.Pos != start {
+		t.Errorf("player at %v, expected %v", world.Player.Pos, start)
+	}
+	if world.Player.Turn != turn {
+		t.Errorf("turn is %+v, expected %+v", world.Player.Turn, turn)
+	}
+	if !wall.Block {
+		t.Error("the wall wasn't restored")
+	}
+	if enemy.Health != health {
+		t.Errorf("enemy health %.2f, expected %.2f", enemy.Health, health)
+	}
+}
+
+func TestUndoBringsBackKilledEnemies(t *testing.T) {
+	world := newCommandTestWorld(t)
+	world.Player.Pos = tilePos(2, 2)
+	enemy := world.enemyAt(tilePos(3, 2))
+	enemy.Health = 1.0
+	rollCritical(world)
+
+	if err := world.Execute(NewAttackCommand(world, enemy.Pos)); err != nil {
+		t.Fatal(err)
+	}
+	world.update(0)
+	if world.enemyAt(tilePos(3, 2)) != nil || world.Kills != 1 {
+		t.Fatal("the killed enemy wasn't removed")
+	}
+
+	world.Commands.Undo(world)
+	if world.enemyAt(tilePos(3, 2)) != enemy || world.Kills != 0 {
+		t.Error("the enemy didn't come back")
+	}
+}
+
+func TestEndTurnCommitsCommands(t *testing.T) {
+	world := newCommandTestWorld(t)
+
+	if err := world.Execute(NewMoveCommand(world, tilePos(2, 1))); err != nil {
+		t.Fatal(err)
+	}
+	if err := world.Execute(&EndTurnCommand{}); err != nil {
+		t.Fatal(err)
+	}
+	if world.Commands.Undo(world) {
+		t.Error("undid a command from an ended turn")
+	}
+	if err := world.Execute(NewMoveCommand(world, tilePos(1, 1))); err == nil {
+		t.Error("moved after the turn ended")
+	}
+
+	world.Turns.RunEnemyPhase()
+	if world.Commands.Undo(world) {
+		t.Error("undid a command from the previous turn")
+	}
+}
+
+func TestCommandsEmitEvents(t *testing.T) {
+	world := newCommandTestWorld(t)
+	world.Execute(NewMoveCommand(world, tilePos(2, 1)))
+	world.Execute(NewMoveCommand(world, tilePos(0, 1)))
+	world.Execute(NewDigCommand(tilePos(3, 1)))
+	world.Commands.Undo(world)
+
+	var kinds []int
+	for _, event := range world.Events.Flush() {
+		kinds = append(kinds, event.Kind)
+	}
+
+	expected := []int{EVENT_MOVED, EVENT_DUG, EVENT_UNDONE}
+	if len(kinds) != len(expected) {
+		t.Fatalf("expected events %v, got %v", expected, kinds)
+	}
+	for i := range expected {
+		if kinds[i] != expected[i] {
+			t.Errorf("expected events %v, got %v", expected, kinds)
+			break
+		}
+	}
+	if len(world.Events.Entries) != len(expected) {
+		t.Errorf("%d events in the log, expected %d", len(world.Events.Entries), len(expected))
+	}
+}
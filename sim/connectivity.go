@@ -0,0 +1,216 @@
+package sim
+
+import (
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Floor regions smaller than this are filled in instead of tunneled to
+const MIN_REGION_SIZE = 12
+
+type gridCell struct {
+	X, Y int
+}
+
+func isPassableGlyph(glyph byte) bool {
+	return glyph != '@' && glyph != '!'
+}
+
+func parseGlyphGrid(mapstring string) glyphGrid {
+	var grid glyphGrid
+	for _, row := range strings.Split(mapstring, "\n") {
+		if row != "" {
+			grid = append(grid, []byte(row))
+		}
+	}
+	return grid
+}
+
+// ConnectMap makes every floor tile of a generated map reachable from every other one.
+// Small pockets are walled over, the rest are joined to the largest region with tunnels.
+func ConnectMap(mapstring string, rng *rand.Rand) string {
+	t := time.Now()
+	grid := parseGlyphGrid(mapstring)
+	regions := findRegions(grid)
+
+	if len(regions) == 0 {
+		if len(grid) > 2 && len(grid[0]) > 2 {
+			grid[len(grid)/2][len(grid[0])/2] = 'P'
+		}
+		return grid.String()
+	}
+
+	// Largest region first, it is the one everything else gets connected to
+	largest := 0
+	for i, region := range regions {
+		if len(region) > len(regions[largest]) {
+			largest = i
+		}
+	}
+
+	discarded := 0
+	var remaining [][]gridCell
+	for i, region := range regions {
+		if i != largest && len(region) < MIN_REGION_SIZE {
+			for _, cell := range region {
+				grid[cell.Y][cell.X] = '@'
+			}
+			discarded++
+		} else if i != largest {
+			remaining = append(remaining, region)
+		}
+	}
+
+	connected := floodFill(grid, regions[largest][0])
+	tunnels := 0
+	for _, region := range remaining {
+		if connected[region[0].Y][region[0].X] {
+			continue
+		}
+
+		from := region[rng.Intn(len(region))]
+		to := nearestConnectedCell(connected, from)
+		carveCorridor(grid, from.X, from.Y, to.X, to.Y, rng.Intn(2) == 0)
+		connected = floodFill(grid, regions[largest][0])
+		tunnels++
+	}
+
+	ensureSpawnPoint(grid, connected, rng)
+
+	log.Printf("Connectivity pass discarded %d regions and dug %d tunnels in %v", discarded, tunnels, time.Since(t))
+	return grid.String()
+}
+
+// Returns every 4-way connected group of passable cells
+func findRegions(grid glyphGrid) [][]gridCell {
+	visited := make([][]bool, len(grid))
+	for y := range grid {
+		visited[y] = make([]bool, len(grid[y]))
+	}
+
+	var regions [][]gridCell
+	for y, row := range grid {
+		for x, glyph := range row {
+			if visited[y][x] || !isPassableGlyph(glyph) {
+				continue
+			}
+
+			var region []gridCell
+			queue := []gridCell{{X: x, Y: y}}
+			visited[y][x] = true
+			for len(queue) > 0 {
+				cell := queue[0]
+				queue = queue[1:]
+				region = append(region, cell)
+
+				forEachGridNeighbour(grid, cell, func(nb gridCell) {
+					if !visited[nb.Y][nb.X] && isPassableGlyph(grid[nb.Y][nb.X]) {
+						visited[nb.Y][nb.X] = true
+						queue = append(queue, nb)
+					}
+				})
+			}
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+func floodFill(grid glyphGrid, start gridCell) [][]bool {
+	reached := make([][]bool, len(grid))
+	for y := range grid {
+		reached[y] = make([]bool, len(grid[y]))
+	}
+
+	queue := []gridCell{start}
+	reached[start.Y][start.X] = true
+	for len(queue) > 0 {
+		cell := queue[0]
+		queue = queue[1:]
+
+		forEachGridNeighbour(grid, cell, func(nb gridCell) {
+			if !reached[nb.Y][nb.X] && isPassableGlyph(grid[nb.Y][nb.X]) {
+				reached[nb.Y][nb.X] = true
+				queue = append(queue, nb)
+			}
+		})
+	}
+	return reached
+}
+
+// Walking distance from start to every reachable cell, -1 for the rest
+func gridDistances(grid glyphGrid, start gridCell) [][]int {
+	distances := make([][]int, len(grid))
+	for y := range grid {
+		distances[y] = make([]int, len(grid[y]))
+		for x := range distances[y] {
+			distances[y][x] = -1
+		}
+	}
+
+	queue := []gridCell{start}
+	distances[start.Y][start.X] = 0
+	for len(queue) > 0 {
+		cell := queue[0]
+		queue = queue[1:]
+
+		forEachGridNeighbour(grid, cell, func(nb gridCell) {
+			if distances[nb.Y][nb.X] < 0 && isPassableGlyph(grid[nb.Y][nb.X]) {
+				distances[nb.Y][nb.X] = distances[cell.Y][cell.X] + 1
+				queue = append(queue, nb)
+			}
+		})
+	}
+	return distances
+}
+
+var gridDirections = [4][2]int{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+
+func forEachGridNeighbour(grid glyphGrid, cell gridCell, fn func(nb gridCell)) {
+	for _, d := range gridDirections {
+		x, y := cell.X+d[0], cell.Y+d[1]
+		if y >= 0 && y < len(grid) && x >= 0 && x < len(grid[y]) {
+			fn(gridCell{X: x, Y: y})
+		}
+	}
+}
+
+func nearestConnectedCell(connected [][]bool, from gridCell) gridCell {
+	nearest := from
+	best := -1
+	for y, row := range connected {
+		for x, ok := range row {
+			if !ok {
+				continue
+			}
+			distance := abs(x-from.X) + abs(y-from.Y)
+			if best < 0 || distance < best {
+				best = distance
+				nearest = gridCell{X: x, Y: y}
+			}
+		}
+	}
+	return nearest
+}
+
+// Generated maps place spawns at random, make sure there is at least one for the player
+func ensureSpawnPoint(grid glyphGrid, connected [][]bool, rng *rand.Rand) {
+	var floor []gridCell
+	for y, row := range grid {
+		for x, glyph := range row {
+			if glyph == 'P' {
+				return
+			}
+			if connected[y][x] && glyph == '_' {
+				floor = append(floor, gridCell{X: x, Y: y})
+			}
+		}
+	}
+
+	if len(floor) > 0 {
+		cell := floor[rng.Intn(len(floor))]
+		grid[cell.Y][cell.X] = 'P'
+	}
+}
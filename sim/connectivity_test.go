@@ -0,0 +1,111 @@
+package sim
+
+import (
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func connectivitySeeds(t *testing.T) int64 {
+	out := log.Writer()
+	log.SetOutput(ioutil.Discard)
+	t.Cleanup(func() { log.SetOutput(out) })
+	if testing.Short() {
+		return 100
+	}
+	return 2000
+}
+
+func checkConnected(t *testing.T, name string, seed int64, mapstring string) {
+	grid := parseGlyphGrid(mapstring)
+	regions := findRegions(grid)
+	if len(regions) != 1 {
+		t.Fatalf("%v seed %d: expected 1 floor region, got %d\n%v", name, seed, len(regions), mapstring)
+	}
+
+	spawns := 0
+	for y, row := range grid {
+		for x, glyph := range row {
+			if glyph == 'P' || glyph == '<' {
+				spawns++
+			}
+			border := y == 0 || x == 0 || y == len(grid)-1 || x == len(row)-1
+			if border && isPassableGlyph(glyph) {
+				t.Fatalf("%v seed %d: floor at border %d,%d", name, seed, x, y)
+			}
+		}
+	}
+	if spawns == 0 {
+		t.Fatalf("%v seed %d: no spawn points\n%v", name, seed, mapstring)
+	}
+}
+
+func TestConnectMapGenerators(t *testing.T) {
+	seeds := connectivitySeeds(t)
+	for _, name := range GeneratorNames() {
+		for seed := int64(0); seed < seeds; seed++ {
+			rng := rand.New(rand.NewSource(seed))
+			generator, _ := GetGenerator(name)
+			mapstring := PlaceStairs(ConnectMap(generator.Generate(DefaultGeneratorConfig(), rng), rng), rng)
+			checkConnected(t, name, seed, mapstring)
+
+			if strings.Count(mapstring, "<") != 1 || strings.Count(mapstring, ">") != 1 {
+				t.Fatalf("%v seed %d: expected one up and one down stairs\n%v", name, seed, mapstring)
+			}
+		}
+	}
+}
+
+func TestConnectMapConfiguredSizes(t *testing.T) {
+	connectivitySeeds(t)
+	for _, name := range GeneratorNames() {
+		for _, size := range []string{"3x3", "12x40", "64x20", "250x180"} {
+			config := DefaultGeneratorConfig()
+			if err := config.Set("size", size); err != nil {
+				t.Fatal(err)
+			}
+
+			generator, _ := GetGenerator(name)
+			rng := rand.New(rand.NewSource(7))
+			grid := parseGlyphGrid(ConnectMap(generator.Generate(config, rng), rng))
+			if len(grid) != config.Height || len(grid[0]) != config.Width {
+				t.Fatalf("%v %v: got a %dx%d map", name, size, len(grid[0]), len(grid))
+			}
+			checkConnected(t, name, 7, grid.String())
+		}
+	}
+}
+
+func TestConnectMapDeterministic(t *testing.T) {
+	generator, _ := GetGenerator("cave")
+	for seed := int64(0); seed < 20; seed++ {
+		a := rand.New(rand.NewSource(seed))
+		b := rand.New(rand.NewSource(seed))
+		if ConnectMap(generator.Generate(DefaultGeneratorConfig(), a), a) != ConnectMap(generator.Generate(DefaultGeneratorConfig(), b), b) {
+			t.Fatalf("seed %d produced different maps", seed)
+		}
+	}
+}
+
+func TestConnectMapSmallPockets(t *testing.T) {
+	mapstring := "" +
+		"@@@@@@@@@@@@@@@@\n" +
+		"@______@@@@@@@@@\n" +
+		"@__P___@@@@_@@@@\n" +
+		"@______@@@@@@@@@\n" +
+		"@@@@@@@@@@@@@@@@\n" +
+		"@@@@@@@@@______@\n" +
+		"@@@@@@@@@______@\n" +
+		"@@@@@@@@@@@@@@@@\n"
+	rng := rand.New(rand.NewSource(1))
+	result := parseGlyphGrid(ConnectMap(mapstring, rng))
+
+	if result[2][11] != '@' {
+		t.Errorf("single tile pocket should have been filled in")
+	}
+	if len(findRegions(result)) != 1 {
+		t.Errorf("expected the two rooms to be tunneled together\n%v", result)
+	}
+}
@@ -0,0 +1,106 @@
+package sim
+
+// HandleControls turns the gameplay actions of the input into commands,
+// the rest of the input is up to the front-end
+func (world *World) HandleControls(input Input) {
+	if !world.Player.Turn.Done {
+		if world.Selection.Using {
+			moveSelectionCursor(&world.Selection, input)
+		} else if dir := moveDirection(input); dir != (IVector2{}) {
+			to := IVector2{X: world.Player.Pos.X + dir.X, Y: world.Player.Pos.Y + dir.Y}
+			world.Execute(NewMoveCommand(world, to))
+		}
+	} else {
+		world.Selection.Using = false
+	}
+
+	if input.Has(INPUT_SELECT) {
+		world.Selection.Pos = world.Player.Pos
+		world.Selection.Using = !world.Selection.Using
+	}
+
+	if input.Has(INPUT_STAIRS) && !world.Player.Turn.Done {
+		world.UseStairs()
+	}
+
+	if world.Selection.Using {
+		if input.Has(INPUT_DIG) {
+			world.Execute(NewDigCommand(world.Selection.Pos))
+		}
+		if input.Has(INPUT_ATTACK) {
+			world.Execute(NewAttackCommand(world, world.Selection.Pos))
+		}
+		if input.Has(INPUT_USE_ABILITY) {
+			world.Execute(NewAbilityCommand(world))
+		}
+	}
+
+	if input.Has(INPUT_NEXT_ITEM) {
+		world.Player.Inventory.Select(1)
+	}
+	if input.Has(INPUT_PREVIOUS_ITEM) {
+		world.Player.Inventory.Select(-1)
+	}
+	if input.Has(INPUT_NEXT_STAT) {
+		world.Player.Progress.SelectStat(1)
+	}
+	if input.Has(INPUT_NEXT_ABILITY) {
+		world.Player.Abilities.Select(world.Data.Abilities, 1)
+	}
+	if !world.Player.Turn.Done {
+		if input.Has(INPUT_PICK_UP) {
+			world.Execute(NewPickUpCommand(world))
+		}
+		if input.Has(INPUT_DROP) {
+			world.Execute(NewDropCommand(world))
+		}
+		if input.Has(INPUT_USE_ITEM) {
+			world.Execute(NewUseItemCommand(world))
+		}
+		if input.Has(INPUT_EQUIP) {
+			world.Execute(NewEquipCommand(world))
+		}
+		if input.Has(INPUT_RAISE_STAT) {
+			world.Execute(NewRaiseStatCommand(world))
+		}
+	}
+
+	if input.Has(INPUT_UNDO) {
+		world.Commands.Undo(world)
+	}
+
+	if DebugMode {
+		if input.Has(INPUT_VISIBILITY_UP) {
+			world.Player.Stats.Visibility++
+		}
+
+		if input.Has(INPUT_VISIBILITY_DOWN) {
+			world.Player.Stats.Visibility--
+		}
+	}
+
+	if input.Has(INPUT_END_TURN) && !world.Player.Turn.Done {
+		world.Execute(&EndTurnCommand{})
+	}
+}
+
+func moveSelectionCursor(selection *SelectionMode, input Input) {
+	s_x := selection.Pos.X
+	s_y := selection.Pos.Y
+
+	if input.Has(INPUT_LEFT) {
+		s_x -= TILE_SIZE
+	}
+	if input.Has(INPUT_RIGHT) {
+		s_x += TILE_SIZE
+	}
+	if input.Has(INPUT_UP) {
+		s_y -= TILE_SIZE
+	}
+	if input.Has(INPUT_DOWN) {
+		s_y += TILE_SIZE
+	}
+
+	selection.Pos.X = s_x
+	selection.Pos.Y = s_y
+}
@@ -0,0 +1,53 @@
+package sim
+
+// Data is what the data files describe, every world is played with the data it was made with
+type Data struct {
+	Items []*ItemKind
+	// In file order, so that spawn tables come out the same every time
+	Archetypes []*Archetype
+	// In file order, which is the order of the hotbar
+	Abilities []*Ability
+}
+
+// DefaultData knows only the built-in goblin, floors are generated without items and the hotbar is empty
+func DefaultData() *Data {
+	return &Data{Archetypes: []*Archetype{&goblinArchetype}}
+}
+
+// LoadData reads the data files the game ships with.
+// Items come first, archetypes name the items they carry.
+func LoadData() (*Data, error) {
+	data := DefaultData()
+	if err := data.LoadItems(ITEMS_FILE); err != nil {
+		return nil, err
+	}
+	if err := data.LoadArchetypes(ARCHETYPES_FILE); err != nil {
+		return nil, err
+	}
+	if err := data.LoadAbilities(ABILITIES_FILE); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (data *Data) GetItemKind(name string) (*ItemKind, bool) {
+	return findItemKind(data.Items, name)
+}
+
+func (data *Data) GetArchetype(name string) (*Archetype, bool) {
+	for _, archetype := range data.Archetypes {
+		if archetype.Name == name {
+			return archetype, true
+		}
+	}
+	return nil, false
+}
+
+func (data *Data) GetAbility(name string) (*Ability, bool) {
+	for _, ability := range data.Abilities {
+		if ability.Name == name {
+			return ability, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,101 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Dice are written like "2d6+1" in the data files: two six-sided dice with 1 added to the sum,
+// or just "2" for a flat amount
+type Dice struct {
+	Count int
+	Sides int
+	Bonus int
+}
+
+func ParseDice(s string) (Dice, error) {
+	var dice Dice
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return dice, nil
+	}
+
+	split := strings.SplitN(s, "d", 2)
+	if len(split) != 2 {
+		// A flat amount without any dice
+		bonus, err := strconv.Atoi(s)
+		if err != nil {
+			return dice, fmt.Errorf("dice should be like 2d6+1, got %q", s)
+		}
+		return Dice{Bonus: bonus}, nil
+	}
+	count, err := strconv.Atoi(split[0])
+	if err != nil || count < 0 {
+		return dice, fmt.Errorf("invalid number of dice in %q", s)
+	}
+
+	sides := split[1]
+	bonus := 0
+	if i := strings.IndexAny(sides, "+-"); i >= 0 {
+		bonus, err = strconv.Atoi(sides[i:])
+		if err != nil {
+			return dice, fmt.Errorf("invalid bonus in %q", s)
+		}
+		sides = sides[:i]
+	}
+	sideCount, err := strconv.Atoi(sides)
+	if err != nil || sideCount < 1 {
+		return dice, fmt.Errorf("invalid number of sides in %q", s)
+	}
+
+	return Dice{Count: count, Sides: sideCount, Bonus: bonus}, nil
+}
+
+func (dice Dice) String() string {
+	if dice.Count == 0 {
+		return strconv.Itoa(dice.Bonus)
+	}
+	s := fmt.Sprintf("%dd%d", dice.Count, dice.Sides)
+	if dice.Bonus > 0 {
+		s += fmt.Sprintf("+%d", dice.Bonus)
+	} else if dice.Bonus < 0 {
+		s += strconv.Itoa(dice.Bonus)
+	}
+	return s
+}
+
+// Roll doesn't touch the random numbers when there is nothing to roll
+func (dice Dice) Roll(rng *rand.Rand) int {
+	sum := dice.Bonus
+	for i := 0; i < dice.Count; i++ {
+		sum += rng.Intn(dice.Sides) + 1
+	}
+	return sum
+}
+
+func (dice Dice) IsZero() bool {
+	return dice == Dice{}
+}
+
+func (dice Dice) MarshalJSON() ([]byte, error) {
+	if dice.IsZero() {
+		return json.Marshal("")
+	}
+	return json.Marshal(dice.String())
+}
+
+func (dice *Dice) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseDice(s)
+	if err != nil {
+		return err
+	}
+	*dice = parsed
+	return nil
+}
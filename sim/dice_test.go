@@ -0,0 +1,64 @@
+package sim
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+)
+
+func TestParseDice(t *testing.T) {
+	cases := map[string]Dice{
+		"":      {},
+		"1d6":   {Count: 1, Sides: 6},
+		"2D4+1": {Count: 2, Sides: 4, Bonus: 1},
+		"3d8-2": {Count: 3, Sides: 8, Bonus: -2},
+		"2":     {Bonus: 2},
+	}
+	for s, expected := range cases {
+		dice, err := ParseDice(s)
+		if err != nil || dice != expected {
+			t.Errorf("%q: expected %+v, got %+v, %v", s, expected, dice, err)
+		}
+	}
+
+	for _, s := range []string{"d", "1d", "d6", "-1d6", "1d0", "1d6+", "one"} {
+		if _, err := ParseDice(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestDiceRoundTrip(t *testing.T) {
+	for _, dice := range []Dice{{}, {Count: 1, Sides: 6}, {Count: 2, Sides: 4, Bonus: 1}, {Count: 1, Sides: 8, Bonus: -1}, {Bonus: 3}} {
+		data, err := json.Marshal(dice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var parsed Dice
+		if err := json.Unmarshal(data, &parsed); err != nil || parsed != dice {
+			t.Errorf("%v came back as %v from %s, %v", dice, parsed, data, err)
+		}
+	}
+}
+
+func TestDiceRoll(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	dice := Dice{Count: 2, Sides: 6, Bonus: 1}
+	seen := make(map[int]bool)
+	for i := 0; i < 1000; i++ {
+		roll := dice.Roll(rng)
+		if roll < 3 || roll > 13 {
+			t.Fatalf("rolled %d with %v", roll, dice)
+		}
+		seen[roll] = true
+	}
+	if len(seen) != 11 {
+		t.Errorf("expected every sum from 3 to 13, got %v", seen)
+	}
+
+	source := NewRandomSource(1, 0)
+	Dice{Bonus: 4}.Roll(rand.New(source))
+	if _, calls := source.State(); calls != 0 {
+		t.Error("a flat amount drew random numbers")
+	}
+}
@@ -0,0 +1,110 @@
+package sim
+
+import (
+	"log"
+)
+
+type Floor struct {
+	Depth   int
+	Seed    int64
+	Map     [][]*Tile
+	Enemies []*Enemy
+	Items   []*FloorItem
+	Entry   IVector2
+	Exit    IVector2
+	HasExit bool
+}
+
+// Dungeon keeps every visited floor so that going back up finds them as they were left
+type Dungeon struct {
+	Seed   int64
+	Level  *LevelFile
+	Floors []*Floor
+	Depth  int
+
+	// Used to generate the floors below
+	data *Data
+}
+
+func NewDungeon(seed int64, level *LevelFile, data *Data) *Dungeon {
+	dungeon := Dungeon{
+		Seed:  seed,
+		Level: level,
+		data:  data,
+	}
+	dungeon.Floors = append(dungeon.Floors, GenerateLevel(dungeon.floorSeed(0), 0, level, data))
+	return &dungeon
+}
+
+func (dungeon *Dungeon) Current() *Floor {
+	return dungeon.Floors[dungeon.Depth]
+}
+
+func (dungeon *Dungeon) floorSeed(depth int) int64 {
+	return dungeon.Seed + int64(depth)*1000003
+}
+
+// Floors below a hand-authored level are generated with the default generator
+func (dungeon *Dungeon) levelForDepth(depth int) *LevelFile {
+	if depth == 0 || dungeon.Level.MapString == "" {
+		return dungeon.Level
+	}
+	return &LevelFile{
+		Name:   dungeon.Level.Name,
+		Config: DefaultGeneratorConfig(),
+	}
+}
+
+func (dungeon *Dungeon) Descend() *Floor {
+	depth := dungeon.Depth + 1
+	if depth >= len(dungeon.Floors) {
+		dungeon.Floors = append(dungeon.Floors, GenerateLevel(dungeon.floorSeed(depth), depth, dungeon.levelForDepth(depth), dungeon.data))
+	}
+	dungeon.Depth = depth
+	return dungeon.Current()
+}
+
+func (dungeon *Dungeon) Ascend() (*Floor, bool) {
+	if dungeon.Depth == 0 {
+		return dungeon.Current(), false
+	}
+	dungeon.Depth--
+	return dungeon.Current(), true
+}
+
+// Moves the player between floors if they are standing on stairs
+func (world *World) UseStairs() {
+	tile, ok := world.GetMapTile(world.Player.Pos)
+	if !ok {
+		return
+	}
+
+	// Enemies and items may have been removed from the slices since the floor was entered
+	world.Dungeon.Current().Enemies = world.Enemies
+	world.Dungeon.Current().Items = world.Items
+
+	switch tile.Type {
+	case TILE_STAIRS_DOWN:
+		floor := world.Dungeon.Descend()
+		world.enterFloor(floor, floor.Entry)
+	case TILE_STAIRS_UP:
+		if floor, ok := world.Dungeon.Ascend(); ok {
+			world.enterFloor(floor, floor.Exit)
+		} else {
+			log.Println("The way back up is sealed")
+		}
+	}
+}
+
+func (world *World) enterFloor(floor *Floor, pos IVector2) {
+	log.Printf("Entered floor %d", floor.Depth+1)
+	world.Map = floor.Map
+	world.Enemies = floor.Enemies
+	world.Items = floor.Items
+	world.Player.Pos = pos
+	world.Selection.Using = false
+	world.Selection.Pos = pos
+	world.Flares = nil
+	// Nothing done on the floor that was left can be taken back
+	world.Commands.Commit()
+}
@@ -0,0 +1,362 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+type EffectKind string
+
+const (
+	EFFECT_POISON EffectKind = "poison"
+	EFFECT_STUN   EffectKind = "stun"
+	EFFECT_BLEED  EffectKind = "bleed"
+	EFFECT_HASTE  EffectKind = "haste"
+	EFFECT_BLIND  EffectKind = "blind"
+)
+
+var effectOrder = []EffectKind{EFFECT_POISON, EFFECT_STUN, EFFECT_BLEED, EFFECT_HASTE, EFFECT_BLIND}
+
+// Damage per stack of poison at the start of every turn
+const POISON_DAMAGE = 1.5
+
+// Damage per stack of bleeding for every tile moved
+const BLEED_DAMAGE = 1.0
+
+// Hits on a stunned character do this much more damage
+const STUN_DAMAGE_MULT = 1.25
+
+const HASTE_MOVEMENT = 3
+const BLIND_VISIBILITY = 5
+
+// Blindness never takes Visibility below this, a character always sees its own tile
+const MIN_BLIND_VISIBILITY = 1
+
+// Effect is a timed effect on a character
+type Effect struct {
+	Kind EffectKind `json:"kind"`
+	// Turns of the character the effect lasts, counting the current one
+	Turns  int `json:"turns"`
+	Stacks int `json:"stacks"`
+}
+
+// Effects is everything on a character, at most one Effect of each kind
+type Effects []Effect
+
+// Infliction is an effect an item puts on whoever its wearer hits, or on whoever uses it.
+// Abilities inflict theirs on the enemies they hit.
+type Infliction struct {
+	Effect EffectKind `json:"effect"`
+	Turns  int        `json:"turns"`
+	// Chance of a hit inflicting the effect, consumables leave it out and always apply theirs
+	Chance float64 `json:"chance,omitempty"`
+}
+
+type Stacking int
+
+const (
+	// Applying it again makes it last as long as the new one if that is longer
+	STACK_REFRESH Stacking = iota
+	// Applying it again adds a stack up to the limit, and refreshes it
+	STACK_INTENSITY
+	// It can't be applied again while it lasts
+	STACK_NONE
+)
+
+// effectHooks is how an effect changes the character it is on, every hook gets the effect with its stacks.
+// Effects only implement the hooks they need on top of noHooks.
+type effectHooks interface {
+	// Called as the character's turn starts, returns the damage the character takes
+	startTurn(effect *Effect, turn *TurnData) float32
+	// Damage the character takes for moving a tile
+	moved(effect *Effect) float32
+	// Changes the damage of a hit the character takes, before armour
+	damaged(effect *Effect, damage float32) float32
+	// Changes the character's effective stats
+	stats(effect *Effect, stats *Stats)
+}
+
+type effectRules struct {
+	Stacking  Stacking
+	MaxStacks int
+	// Used in the combat log, "the goblin is poisoned"
+	Adjective string
+	hooks     effectHooks
+}
+
+var effectKinds = map[EffectKind]effectRules{
+	EFFECT_POISON: {STACK_INTENSITY, 3, "poisoned", poisonHooks{}},
+	EFFECT_STUN:   {STACK_NONE, 1, "stunned", stunHooks{}},
+	EFFECT_BLEED:  {STACK_INTENSITY, 5, "bleeding", bleedHooks{}},
+	EFFECT_HASTE:  {STACK_REFRESH, 1, "hasted", hasteHooks{}},
+	EFFECT_BLIND:  {STACK_REFRESH, 1, "blinded", blindHooks{}},
+}
+
+type noHooks struct{}
+
+func (noHooks) startTurn(effect *Effect, turn *TurnData) float32 {
+	return 0.0
+}
+
+func (noHooks) moved(effect *Effect) float32 {
+	return 0.0
+}
+
+func (noHooks) damaged(effect *Effect, damage float32) float32 {
+	return damage
+}
+
+func (noHooks) stats(effect *Effect, stats *Stats) {}
+
+type poisonHooks struct{ noHooks }
+
+func (poisonHooks) startTurn(effect *Effect, turn *TurnData) float32 {
+	return POISON_DAMAGE * float32(effect.Stacks)
+}
+
+// Stunned characters can still move, but can't act and take harder hits
+type stunHooks struct{ noHooks }
+
+func (stunHooks) startTurn(effect *Effect, turn *TurnData) float32 {
+	turn.Actions = 0
+	return 0.0
+}
+
+func (stunHooks) damaged(effect *Effect, damage float32) float32 {
+	return damage * STUN_DAMAGE_MULT
+}
+
+type bleedHooks struct{ noHooks }
+
+func (bleedHooks) moved(effect *Effect) float32 {
+	return BLEED_DAMAGE * float32(effect.Stacks)
+}
+
+type hasteHooks struct{ noHooks }
+
+func (hasteHooks) stats(effect *Effect, stats *Stats) {
+	stats.Movement = addBonus(stats.Movement, HASTE_MOVEMENT)
+}
+
+// Blindness shortens how far the character sees, and with it the range of InVisRange
+type blindHooks struct{ noHooks }
+
+func (blindHooks) stats(effect *Effect, stats *Stats) {
+	stats.Visibility = addBonus(stats.Visibility, -BLIND_VISIBILITY)
+	if stats.Visibility < MIN_BLIND_VISIBILITY {
+		stats.Visibility = MIN_BLIND_VISIBILITY
+	}
+}
+
+func (effect *Effect) rules() effectRules {
+	if rules, ok := effectKinds[effect.Kind]; ok {
+		return rules
+	}
+	return effectRules{MaxStacks: 1, Adjective: string(effect.Kind), hooks: noHooks{}}
+}
+
+func (effect Effect) String() string {
+	if effect.Stacks > 1 {
+		return fmt.Sprintf("%v x%d", effect.Kind, effect.Stacks)
+	}
+	return string(effect.Kind)
+}
+
+func validEffect(kind EffectKind) bool {
+	_, ok := effectKinds[kind]
+	return ok
+}
+
+// validate checks an infliction of the named item or ability, always for ones applied without a roll
+func (infliction Infliction) validate(name string, always bool) error {
+	switch {
+	case !validEffect(infliction.Effect):
+		return fmt.Errorf("%v has unknown effect %q, expected one of %v", name, infliction.Effect, effectOrder)
+	case infliction.Turns < 1:
+		return fmt.Errorf("%v inflicts %v for %d turns", name, infliction.Effect, infliction.Turns)
+	case always && infliction.Chance != 0:
+		return fmt.Errorf("%v always applies %v when used, it has no chance", name, infliction.Effect)
+	case !always && (infliction.Chance <= 0 || infliction.Chance > 1):
+		return fmt.Errorf("%v has a chance of %v to inflict %v, expected more than 0 and at most 1", name, infliction.Chance, infliction.Effect)
+	}
+	return nil
+}
+
+func (effects Effects) Get(kind EffectKind) (Effect, bool) {
+	for _, effect := range effects {
+		if effect.Kind == kind {
+			return effect, true
+		}
+	}
+	return Effect{}, false
+}
+
+// add applies an effect by its stacking rules, false if it couldn't be applied again
+func (effects *Effects) add(kind EffectKind, turns int) bool {
+	for i := range *effects {
+		effect := &(*effects)[i]
+		if effect.Kind != kind {
+			continue
+		}
+		rules := effect.rules()
+		switch rules.Stacking {
+		case STACK_NONE:
+			return false
+		case STACK_INTENSITY:
+			if effect.Stacks < rules.MaxStacks {
+				effect.Stacks++
+			}
+		}
+		if turns > effect.Turns {
+			effect.Turns = turns
+		}
+		return true
+	}
+	*effects = append(*effects, Effect{Kind: kind, Turns: turns, Stacks: 1})
+	return true
+}
+
+func (effects Effects) copy() Effects {
+	return append(Effects(nil), effects...)
+}
+
+// Stats with the changes of every effect
+func (effects Effects) stats(stats Stats) Stats {
+	for i := range effects {
+		effects[i].rules().hooks.stats(&effects[i], &stats)
+	}
+	return stats
+}
+
+func (effects Effects) damaged(damage float32) float32 {
+	for i := range effects {
+		damage = effects[i].rules().hooks.damaged(&effects[i], damage)
+	}
+	return damage
+}
+
+// Damage done by a single effect, the combat log names the effect as its cause
+type effectDamage struct {
+	Kind   EffectKind
+	Damage float32
+}
+
+func (effects Effects) moved() []effectDamage {
+	var damages []effectDamage
+	for i := range effects {
+		if damage := effects[i].rules().hooks.moved(&effects[i]); damage > 0.0 {
+			damages = append(damages, effectDamage{effects[i].Kind, damage})
+		}
+	}
+	return damages
+}
+
+func (effects Effects) startTurn(turn *TurnData) []effectDamage {
+	var damages []effectDamage
+	for i := range effects {
+		if damage := effects[i].rules().hooks.startTurn(&effects[i], turn); damage > 0.0 {
+			damages = append(damages, effectDamage{effects[i].Kind, damage})
+		}
+	}
+	return damages
+}
+
+// countDown takes a turn off every effect and returns the ones that wore off
+func (effects *Effects) countDown() []Effect {
+	var ended []Effect
+	kept := (*effects)[:0]
+	for _, effect := range *effects {
+		effect.Turns--
+		if effect.Turns > 0 {
+			kept = append(kept, effect)
+		} else {
+			ended = append(ended, effect)
+		}
+	}
+	*effects = kept
+	return ended
+}
+
+// Inflictions of the equipped items in gear, rolled on every hit
+func gearInflictions(gear []*Item) []Infliction {
+	var inflictions []Infliction
+	for _, item := range gear {
+		if item.Equipped {
+			inflictions = append(inflictions, item.Effects...)
+		}
+	}
+	return inflictions
+}
+
+func characterName(character Character) string {
+	if enemy, ok := character.(*Enemy); ok {
+		return enemy.Kind
+	}
+	return "player"
+}
+
+// affect puts an effect on the character
+func (world *World) affect(character Character, kind EffectKind, turns int) {
+	effects := character.GetEffects()
+	if !effects.add(kind, turns) {
+		return
+	}
+	effect, _ := effects.Get(kind)
+	world.emit(EVENT_AFFECTED, character.GetPos(), "The %v is %v (%v, %d turns)", characterName(character), effect.rules().Adjective, effect, effect.Turns)
+}
+
+// inflict rolls the attacker's inflictions after a hit on the target
+func (world *World) inflict(attacker *Combatant, target Character, rng *rand.Rand) {
+	for _, infliction := range attacker.Inflicts {
+		if rng.Float64() < infliction.Chance {
+			world.affect(target, infliction.Effect, infliction.Turns)
+		}
+	}
+}
+
+// startTurn starts the character's turn and runs the start of turn hooks of its effects
+func (world *World) startTurn(character Character) {
+	character.StartTurn()
+	for _, damage := range character.GetEffects().startTurn(character.GetTurn()) {
+		world.hurt(character, damage.Damage, string(damage.Kind))
+	}
+}
+
+// endTurn counts down the character's effects once its turn is over
+func (world *World) endTurn(character Character) {
+	for _, effect := range character.GetEffects().countDown() {
+		world.emit(EVENT_EFFECT_ENDED, character.GetPos(), "The %v is no longer %v", characterName(character), effect.rules().Adjective)
+	}
+}
+
+// The character moved a tile
+func (world *World) moved(character Character) {
+	for _, damage := range character.GetEffects().moved() {
+		world.hurt(character, damage.Damage, string(damage.Kind))
+	}
+}
+
+// hurt takes damage from effects off the character, enemies killed by them are worth experience like any other kill
+func (world *World) hurt(character Character, damage float32, cause string) {
+	switch c := character.(type) {
+	case *Player:
+		if c.IsDead() {
+			return
+		}
+		c.Health -= damage
+		world.emit(EVENT_EFFECT_DAMAGE, c.Pos, "Took %.1f damage from %v, leaving %.1f health", damage, cause, c.Health)
+		if c.IsDead() {
+			world.emit(EVENT_PLAYER_DIED, c.Pos, "Player died on floor %d", world.Dungeon.Depth+1)
+		}
+	case *Enemy:
+		if c.Health <= 0.0 {
+			return
+		}
+		c.Health -= damage
+		world.emit(EVENT_EFFECT_DAMAGE, c.Pos, "Enemy %v took %.1f damage from %v, leaving %.1f health", c.Kind, damage, cause, c.Health)
+		if c.Health <= 0.0 {
+			world.emit(EVENT_KILLED, c.Pos, "Killed %v at %v for %d experience", c.Kind, tilePoint(c.Pos), ExperienceFor(c))
+			world.awardExperience(c)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package sim
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestEffectStacking(t *testing.T) {
+	var effects Effects
+	for i := 0; i < 5; i++ {
+		effects.add(EFFECT_POISON, 2+i)
+	}
+	if poison, _ := effects.Get(EFFECT_POISON); poison.Stacks != 3 || poison.Turns != 6 {
+		t.Errorf("poison should stack up to 3 and last the longest, got %+v", poison)
+	}
+
+	if !effects.add(EFFECT_STUN, 1) || effects.add(EFFECT_STUN, 3) {
+		t.Error("stun was applied again while it lasted")
+	}
+	effects.add(EFFECT_HASTE, 3)
+	effects.add(EFFECT_HASTE, 1)
+	if haste, _ := effects.Get(EFFECT_HASTE); haste.Stacks != 1 || haste.Turns != 3 {
+		t.Errorf("haste should only refresh, got %+v", haste)
+	}
+	if len(effects) != 3 {
+		t.Errorf("expected one effect of each kind, got %v", effects)
+	}
+}
+
+func TestEffectsWearOff(t *testing.T) {
+	world := newCommandTestWorld(t)
+	enemy := world.Enemies[0]
+	world.affect(enemy, EFFECT_BLIND, 2)
+	world.affect(enemy, EFFECT_STUN, 1)
+
+	world.startTurn(enemy)
+	if enemy.Turn.Actions != 0 {
+		t.Error("the stunned enemy can still act")
+	}
+	world.endTurn(enemy)
+	if _, ok := enemy.Effects.Get(EFFECT_STUN); ok {
+		t.Error("the stun lasted longer than a turn")
+	}
+
+	world.startTurn(enemy)
+	if enemy.Turn.Actions == 0 {
+		t.Error("the enemy is still stunned")
+	}
+	world.endTurn(enemy)
+	if len(enemy.Effects) != 0 {
+		t.Errorf("effects left after they should have worn off: %v", enemy.Effects)
+	}
+}
+
+func TestPoisonAndBleeding(t *testing.T) {
+	world := newCommandTestWorld(t)
+	player := world.Player
+	world.affect(player, EFFECT_POISON, 2)
+	world.affect(player, EFFECT_POISON, 2)
+
+	health := player.Health
+	world.startTurn(player)
+	if player.Health != health-2*POISON_DAMAGE {
+		t.Errorf("two stacks of poison left %v health out of %v", player.Health, health)
+	}
+
+	world.affect(player, EFFECT_BLEED, 2)
+	health = player.Health
+	if err := world.Execute(NewMoveCommand(world, tilePos(2, 1))); err != nil {
+		t.Fatal(err)
+	}
+	if player.Health != health-BLEED_DAMAGE {
+		t.Errorf("moving while bleeding left %v health out of %v", player.Health, health)
+	}
+	if world.Commands.Undo(world) || player.Pos != tilePos(2, 1) {
+		t.Error("the move that hurt the player was undone")
+	}
+}
+
+func TestBleedingToDeathIsFinal(t *testing.T) {
+	world := newCommandTestWorld(t)
+	world.affect(world.Player, EFFECT_BLEED, 2)
+	if err := world.Execute(NewMoveCommand(world, tilePos(2, 1))); err != nil {
+		t.Fatal(err)
+	}
+	world.Player.Health = BLEED_DAMAGE
+	if err := world.Execute(NewMoveCommand(world, tilePos(1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	if !world.Player.IsDead() || world.Commands.Undo(world) {
+		t.Error("the player bled to death and took the step back")
+	}
+}
+
+func TestPoisonKillsSeveralInARound(t *testing.T) {
+	world := newTestWorld(t, 1, `
+@@@@@@@@@@
+@P_____g_@
+@______g_@
+@@@@@@@@@@
+`)
+	for _, enemy := range world.Enemies {
+		world.affect(enemy, EFFECT_POISON, 2)
+		enemy.Health = 0.1
+	}
+	world.Events.Flush()
+
+	world.EndTurn()
+	world.update(0)
+	world.update(0)
+	if len(world.Enemies) != 0 || world.Kills != 2 {
+		t.Errorf("%d enemies left and %d kills after the poison", len(world.Enemies), world.Kills)
+	}
+	damaged := 0
+	for _, event := range world.Events.Flush() {
+		if event.Kind == EVENT_EFFECT_DAMAGE {
+			damaged++
+			if !strings.Contains(event.Message, string(EFFECT_POISON)) {
+				t.Errorf("poison damage reported as %q", event.Message)
+			}
+		}
+	}
+	if damaged != 2 {
+		t.Errorf("expected both enemies to take poison damage, got %d", damaged)
+	}
+}
+
+func TestStatEffects(t *testing.T) {
+	world := newTestWorld(t, 1, `
+@@@@@@@@
+@P___g_@
+@@@@@@@@
+`)
+	enemy := world.Enemies[0]
+	if !enemy.CanSeePlayer(world) {
+		t.Fatal("the enemy can't see the player to begin with")
+	}
+
+	world.affect(enemy, EFFECT_BLIND, 1)
+	if enemy.EffectiveStats().Visibility != enemy.Stats.Visibility-BLIND_VISIBILITY {
+		t.Errorf("blind enemy has %d visibility", enemy.EffectiveStats().Visibility)
+	}
+	if enemy.CanSeePlayer(world) {
+		t.Error("the blind enemy sees the player 4 tiles away")
+	}
+
+	world.affect(world.Player, EFFECT_HASTE, 1)
+	world.startTurn(world.Player)
+	if world.Player.Turn.Movement != world.Player.Stats.Movement+HASTE_MOVEMENT {
+		t.Errorf("hasted player got %d movement", world.Player.Turn.Movement)
+	}
+}
+
+func TestBlindnessLeavesSomeSight(t *testing.T) {
+	world := newCommandTestWorld(t)
+	enemy := world.Enemies[0]
+	enemy.Stats.Visibility = BLIND_VISIBILITY
+	world.affect(enemy, EFFECT_BLIND, 1)
+
+	visibility := enemy.EffectiveStats().Visibility
+	if visibility != MIN_BLIND_VISIBILITY {
+		t.Fatalf("blind enemy with %d visibility has %d", BLIND_VISIBILITY, visibility)
+	}
+	enemy.Sight = world.ComputeFOV(enemy.Pos, visibility)
+	if len(enemy.Sight) < 2 {
+		t.Errorf("the blind enemy only sees %v", enemy.Sight)
+	}
+	tile, _ := world.GetMapTile(enemy.Pos)
+	if light := enemy.LightEmittedToTile(tile); light != 255 {
+		t.Errorf("the blind enemy lights its own tile to %d", light)
+	}
+	if light := calculateLightLevel(0, 0); light != 0 {
+		t.Errorf("no visibility still gives %d light", light)
+	}
+}
+
+func TestStunnedTakesMoreDamage(t *testing.T) {
+	attacker := Combatant{Stats: Stats{Strength: 4, Dexterity: 100}}
+	defender := Combatant{}
+	stunned := Combatant{Effects: Effects{{Kind: EFFECT_STUN, Turns: 1, Stacks: 1}}}
+
+	normal := ResolveAttack(&attacker, &defender, rand.New(rand.NewSource(3)))
+	harder := ResolveAttack(&attacker, &stunned, rand.New(rand.NewSource(3)))
+	if !normal.Hit || harder.Damage != normal.Damage*STUN_DAMAGE_MULT {
+		t.Errorf("a stunned defender took %v damage, %v otherwise", harder.Damage, normal.Damage)
+	}
+}
+
+func TestHitsInflictEffects(t *testing.T) {
+	world := newCommandTestWorld(t)
+	world.Player.Pos = tilePos(2, 2)
+	enemy := world.enemyAt(tilePos(3, 2))
+	enemy.Health = 1000.0
+	world.Player.Inventory.Items = []*Item{{
+		Name:     "venom knife",
+		Category: ITEM_WEAPON,
+		Equipped: true,
+		Effects:  []Infliction{{Effect: EFFECT_POISON, Turns: 3, Chance: 1}},
+	}}
+	rollCritical(world)
+
+	if err := world.Execute(NewAttackCommand(world, enemy.Pos)); err != nil {
+		t.Fatal(err)
+	}
+	if poison, ok := enemy.Effects.Get(EFFECT_POISON); !ok || poison.Turns != 3 {
+		t.Errorf("the hit didn't poison the enemy: %v", enemy.Effects)
+	}
+	world.Commands.Undo(world)
+	if len(enemy.Effects) != 0 {
+		t.Errorf("undo left the enemy with %v", enemy.Effects)
+	}
+}
+
+func TestConsumableEffects(t *testing.T) {
+	world := newCommandTestWorld(t)
+	draught := &Item{Name: "haste draught", Category: ITEM_CONSUMABLE, Effects: []Infliction{{Effect: EFFECT_HASTE, Turns: 3}}}
+	world.Player.Inventory.Items = []*Item{draught}
+
+	if err := world.Execute(NewUseItemCommand(world)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := world.Player.Effects.Get(EFFECT_HASTE); !ok {
+		t.Error("the draught didn't haste the player")
+	}
+	world.Commands.Undo(world)
+	if len(world.Player.Effects) != 0 {
+		t.Errorf("undo left the player with %v", world.Player.Effects)
+	}
+}
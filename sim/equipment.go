@@ -0,0 +1,132 @@
+package sim
+
+// The slots a character can equip an item to, an item goes to the slot of its category
+var equipmentSlots = []ItemCategory{ITEM_WEAPON, ITEM_ARMOUR, ITEM_TRINKET}
+
+// StatBonus is added to the stats of whoever has the item equipped, it can be negative
+type StatBonus struct {
+	Movement   int8
+	Visibility int8
+	Vitality   int8
+	Strength   int8
+	Dexterity  int8
+}
+
+func (item *Item) Equippable() bool {
+	for _, slot := range equipmentSlots {
+		if item.Category == slot {
+			return true
+		}
+	}
+	return false
+}
+
+// The item gear has equipped to the slot, if any
+func equippedTo(gear []*Item, slot ItemCategory) *Item {
+	for _, item := range gear {
+		if item.Equipped && item.Category == slot {
+			return item
+		}
+	}
+	return nil
+}
+
+func addBonus(stat uint8, bonus int8) uint8 {
+	value := int(stat) + int(bonus)
+	if value < 0 {
+		return 0
+	}
+	if value > 255 {
+		return 255
+	}
+	return uint8(value)
+}
+
+// Stats with the bonuses of the equipped items in gear added
+func effectiveStats(base Stats, gear []*Item) Stats {
+	bonus := StatBonus{}
+	for _, item := range gear {
+		if item.Equipped {
+			bonus.Movement += item.Bonus.Movement
+			bonus.Visibility += item.Bonus.Visibility
+			bonus.Vitality += item.Bonus.Vitality
+			bonus.Strength += item.Bonus.Strength
+			bonus.Dexterity += item.Bonus.Dexterity
+		}
+	}
+
+	return Stats{
+		Movement:   addBonus(base.Movement, bonus.Movement),
+		Visibility: addBonus(base.Visibility, bonus.Visibility),
+		Vitality:   addBonus(base.Vitality, bonus.Vitality),
+		Strength:   addBonus(base.Strength, bonus.Strength),
+		Dexterity:  addBonus(base.Dexterity, bonus.Dexterity),
+	}
+}
+
+// Damage dice of the equipped items, rolled on every hit
+func gearDamage(gear []*Item) []Dice {
+	var dice []Dice
+	for _, item := range gear {
+		if item.Equipped && !item.Damage.IsZero() {
+			dice = append(dice, item.Damage)
+		}
+	}
+	return dice
+}
+
+// Damage taken off every hit
+func gearArmour(gear []*Item) float32 {
+	armour := float32(0.0)
+	for _, item := range gear {
+		if item.Equipped {
+			armour += item.Armour
+		}
+	}
+	return armour
+}
+
+// The player's gear is whatever they have equipped from their inventory
+func (player *Player) EffectiveStats() Stats {
+	return player.Effects.stats(effectiveStats(player.Stats, player.Inventory.Items))
+}
+
+func (player *Player) Equipped(slot ItemCategory) *Item {
+	return equippedTo(player.Inventory.Items, slot)
+}
+
+func (player *Player) Armour() float32 {
+	return gearArmour(player.Inventory.Items)
+}
+
+// Equipping an item to a slot that is taken puts the previous one away, the player's health
+// is kept within a lowered max health
+func (player *Player) equip(item *Item) *Item {
+	previous := player.Equipped(item.Category)
+	if previous != nil {
+		previous.Equipped = false
+	}
+	item.Equipped = true
+	player.clampHealth()
+	return previous
+}
+
+func (player *Player) unequip(item *Item) {
+	item.Equipped = false
+	player.clampHealth()
+}
+
+func (player *Player) clampHealth() {
+	if max := player.MaxHealth(); player.Health > max {
+		player.Health = max
+	}
+}
+
+// Enemies always have all of their gear equipped
+func (enemy *Enemy) EffectiveStats() Stats {
+	return enemy.Effects.stats(effectiveStats(enemy.Stats, enemy.Gear))
+}
+
+func (enemy *Enemy) Armour() float32 {
+	return gearArmour(enemy.Gear)
+}
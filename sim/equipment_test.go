@@ -0,0 +1,110 @@
+package sim
+
+import (
+	"testing"
+)
+
+func TestEffectiveStats(t *testing.T) {
+	base := Stats{Movement: 4, Visibility: 6, Vitality: 5, Strength: 3, Dexterity: 1}
+	gear := []*Item{
+		{Name: "hammer", Category: ITEM_WEAPON, Equipped: true, Bonus: StatBonus{Strength: 2, Dexterity: -3}},
+		{Name: "lamp", Category: ITEM_TRINKET, Equipped: true, Bonus: StatBonus{Visibility: 3}},
+		{Name: "boots", Category: ITEM_ARMOUR, Bonus: StatBonus{Movement: 5}},
+	}
+
+	stats := effectiveStats(base, gear)
+	expected := Stats{Movement: 4, Visibility: 9, Vitality: 5, Strength: 5, Dexterity: 0}
+	if stats != expected {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+	if base.Strength != 3 {
+		t.Error("the base stats were changed")
+	}
+}
+
+func TestEquipCommand(t *testing.T) {
+	world := newCommandTestWorld(t)
+	player := world.Player
+	dagger := &Item{Name: "dagger", Category: ITEM_WEAPON, Bonus: StatBonus{Dexterity: 1}}
+	charm := &Item{Name: "charm", Category: ITEM_TRINKET, Bonus: StatBonus{Vitality: 2}}
+	axe := &Item{Name: "axe", Category: ITEM_WEAPON, Bonus: StatBonus{Strength: 2}}
+	bread := &Item{Name: "bread", Category: ITEM_CONSUMABLE}
+	player.Inventory.Items = []*Item{dagger, charm, axe, bread}
+
+	player.Inventory.Selected = 3
+	if world.Execute(NewEquipCommand(world)) == nil {
+		t.Error("equipped bread")
+	}
+
+	player.Inventory.Selected = 0
+	world.Execute(NewEquipCommand(world))
+	player.Inventory.Selected = 1
+	world.Execute(NewEquipCommand(world))
+	if !dagger.Equipped || !charm.Equipped {
+		t.Fatal("items weren't equipped")
+	}
+	if player.EffectiveStats().Vitality != player.Stats.Vitality+2 || player.MaxHealth() != float32(player.Stats.Vitality+2)*PLAYER_HEALTH_MULT {
+		t.Error("the charm didn't raise vitality")
+	}
+
+	// Equipping to a taken slot puts the previous item away
+	player.Inventory.Selected = 2
+	world.Execute(NewEquipCommand(world))
+	if dagger.Equipped || !axe.Equipped || player.Equipped(ITEM_WEAPON) != axe {
+		t.Error("the axe didn't replace the dagger")
+	}
+	world.Commands.Undo(world)
+	if !dagger.Equipped || axe.Equipped {
+		t.Error("undo didn't bring the dagger back")
+	}
+
+	// Taking off the charm lowers the max health, and the health with it
+	player.Health = player.MaxHealth()
+	player.Inventory.Selected = 1
+	player.Turn.Actions = 1
+	world.Execute(NewEquipCommand(world))
+	if charm.Equipped || player.Health != player.MaxHealth() {
+		t.Errorf("health %v is over the max health %v", player.Health, player.MaxHealth())
+	}
+	world.Commands.Undo(world)
+	if !charm.Equipped || player.Health != player.MaxHealth() {
+		t.Error("undo didn't restore the health")
+	}
+}
+
+func TestDropUnequips(t *testing.T) {
+	world := newCommandTestWorld(t)
+	boots := &Item{Name: "boots", Category: ITEM_ARMOUR, Equipped: true, Bonus: StatBonus{Movement: 2}}
+	world.Player.Inventory.Items = []*Item{boots}
+
+	world.Execute(NewDropCommand(world))
+	if boots.Equipped || world.Player.EffectiveStats().Movement != world.Player.Stats.Movement {
+		t.Error("the dropped boots are still worn")
+	}
+	world.Commands.Undo(world)
+	if !boots.Equipped {
+		t.Error("undo didn't put the boots back on")
+	}
+}
+
+func TestEnemyGear(t *testing.T) {
+	data := loadShippedData(t)
+	archetype := goblinArchetype
+	archetype.Gear = []string{"leather jerkin", "lucky rabbit foot"}
+	if err := archetype.validate(data.Items); err != nil {
+		t.Fatal(err)
+	}
+
+	enemy := archetype.NewEnemy(tilePos(1, 1), 0)
+	if len(enemy.Gear) != 2 || !enemy.Gear[0].Equipped || !enemy.Gear[1].Equipped {
+		t.Fatalf("enemy isn't wearing its gear: %v", enemy.Gear)
+	}
+	if enemy.Armour() <= 0 || enemy.EffectiveStats().Dexterity <= enemy.Stats.Dexterity {
+		t.Errorf("gear didn't help the enemy: armour %v, stats %+v", enemy.Armour(), enemy.EffectiveStats())
+	}
+
+	kind, _ := data.GetItemKind("leather jerkin")
+	if kind.Equipped {
+		t.Error("equipping the enemy's gear changed the item kind")
+	}
+}
@@ -0,0 +1,72 @@
+package sim
+
+import (
+	"fmt"
+)
+
+const (
+	EVENT_MOVED         = iota
+	EVENT_DUG           = iota
+	EVENT_ATTACKED      = iota
+	EVENT_KILLED        = iota
+	EVENT_TURN_ENDED    = iota
+	EVENT_UNDONE        = iota
+	EVENT_ROUND_ENDED   = iota
+	EVENT_PLAYER_HIT    = iota
+	EVENT_PLAYER_DIED   = iota
+	EVENT_ALERTED       = iota
+	EVENT_PICKED_UP     = iota
+	EVENT_DROPPED       = iota
+	EVENT_USED_ITEM     = iota
+	EVENT_EQUIPPED      = iota
+	EVENT_MISSED        = iota
+	EVENT_DODGED        = iota
+	EVENT_LEVELLED_UP   = iota
+	EVENT_RAISED_STAT   = iota
+	EVENT_AFFECTED      = iota
+	EVENT_EFFECT_DAMAGE = iota
+	EVENT_EFFECT_ENDED  = iota
+	EVENT_USED_ABILITY  = iota
+)
+
+// Oldest events are dropped past this
+const MAX_EVENTS = 200
+
+type Event struct {
+	Kind    int
+	Round   int
+	Pos     IVector2
+	Message string
+}
+
+// EventLog keeps the latest events of the game and the ones the front-end hasn't been handed yet
+type EventLog struct {
+	Entries []Event
+	// Not yet handed to the front-end
+	pending []Event
+}
+
+func (events *EventLog) Emit(event Event) {
+	events.Entries = append(events.Entries, event)
+	events.pending = append(events.pending, event)
+	if len(events.Entries) > MAX_EVENTS {
+		events.Entries = events.Entries[len(events.Entries)-MAX_EVENTS:]
+	}
+	debugPrint(event.Message)
+}
+
+// Flush returns the events emitted since the last call
+func (events *EventLog) Flush() []Event {
+	pending := events.pending
+	events.pending = nil
+	return pending
+}
+
+func (world *World) emit(kind int, pos IVector2, format string, args ...interface{}) {
+	world.Events.Emit(Event{
+		Kind:    kind,
+		Round:   world.Turns.Round,
+		Pos:     pos,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
@@ -0,0 +1,90 @@
+package sim
+
+// Each level takes LEVEL_EXPERIENCE more than the last: 50 experience for level 2, 150 in total for level 3 and so on
+const LEVEL_EXPERIENCE = 50
+
+// Stat points earned with every level, spent one at a time with RaiseStatCommand
+const STAT_POINTS_PER_LEVEL = 2
+
+// Points can't raise a stat past this, gear can still take it further
+const MAX_LEVELLED_STAT = 20
+
+// Experience per point of the killed enemy's Vitality, Strength and Dexterity
+const EXPERIENCE_PER_STAT = 2
+
+// The stats points can be spent on, in the order the character panel lists them
+var LevelledStats = []string{"strength", "dexterity", "vitality", "movement", "visibility"}
+
+// Progress is how far the player has levelled up
+type Progress struct {
+	Level      int
+	Experience int
+	// Earned by levelling up and not spent yet
+	Points int
+	// Index of the stat in LevelledStats the next point goes to
+	Selected int
+}
+
+// LevelThreshold is the total experience needed to reach the level
+func LevelThreshold(level int) int {
+	return LEVEL_EXPERIENCE * level * (level - 1) / 2
+}
+
+// NextLevel is the total experience needed for the level after the current one
+func (progress *Progress) NextLevel() int {
+	return LevelThreshold(progress.Level + 1)
+}
+
+// gain adds the experience and returns how many levels it took the player up
+func (progress *Progress) gain(experience int) int {
+	progress.Experience += experience
+	levels := 0
+	for progress.Experience >= progress.NextLevel() {
+		progress.Level++
+		progress.Points += STAT_POINTS_PER_LEVEL
+		levels++
+	}
+	return levels
+}
+
+// SelectStat moves the stat selection by offset, wrapping around at either end
+func (progress *Progress) SelectStat(offset int) {
+	count := len(LevelledStats)
+	progress.Selected = ((progress.Selected+offset)%count + count) % count
+}
+
+// levelled returns the stat of the given index in LevelledStats
+func (stats *Stats) levelled(index int) *uint8 {
+	switch index {
+	case 0:
+		return &stats.Strength
+	case 1:
+		return &stats.Dexterity
+	case 2:
+		return &stats.Vitality
+	case 3:
+		return &stats.Movement
+	case 4:
+		return &stats.Visibility
+	}
+	return nil
+}
+
+// ExperienceFor is what killing the enemy is worth, the tougher it is the more
+func ExperienceFor(enemy *Enemy) int {
+	stats := &enemy.Stats
+	return EXPERIENCE_PER_STAT * (int(stats.Vitality) + int(stats.Strength) + int(stats.Dexterity))
+}
+
+// restoreProgress undoes experience gained since the progress was taken, the stat selection stays where it is
+func (player *Player) restoreProgress(progress Progress) {
+	progress.Selected = player.Progress.Selected
+	player.Progress = progress
+}
+
+func (world *World) awardExperience(enemy *Enemy) {
+	progress := &world.Player.Progress
+	if levels := progress.gain(ExperienceFor(enemy)); levels > 0 {
+		world.emit(EVENT_LEVELLED_UP, world.Player.Pos, "Reached level %d with %d stat points to spend", progress.Level, progress.Points)
+	}
+}
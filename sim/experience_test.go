@@ -0,0 +1,81 @@
+package sim
+
+import (
+	"testing"
+)
+
+func TestLevelThresholds(t *testing.T) {
+	progress := Progress{Level: 1}
+	if levels := progress.gain(LevelThreshold(2) - 1); levels != 0 || progress.Level != 1 {
+		t.Errorf("levelled up to %d without enough experience", progress.Level)
+	}
+
+	// Enough for two levels at once
+	if levels := progress.gain(LevelThreshold(3) - progress.Experience); levels != 2 {
+		t.Errorf("gained %d levels, expected 2", levels)
+	}
+	if progress.Level != 3 || progress.Points != 2*STAT_POINTS_PER_LEVEL {
+		t.Errorf("expected level 3 with %d points, got %+v", 2*STAT_POINTS_PER_LEVEL, progress)
+	}
+	if progress.NextLevel() <= progress.Experience {
+		t.Errorf("next level at %d is already reached", progress.NextLevel())
+	}
+}
+
+func TestKillsGiveExperience(t *testing.T) {
+	world := newCommandTestWorld(t)
+	world.Player.Pos = tilePos(2, 2)
+	enemy := world.enemyAt(tilePos(3, 2))
+	enemy.Health = 1.0
+	world.Player.Progress.Experience = LevelThreshold(2) - 1
+	rollCritical(world)
+
+	if err := world.Execute(NewAttackCommand(world, enemy.Pos)); err != nil {
+		t.Fatal(err)
+	}
+	progress := world.Player.Progress
+	if progress.Experience != LevelThreshold(2)-1+ExperienceFor(enemy) || progress.Level != 2 || progress.Points != STAT_POINTS_PER_LEVEL {
+		t.Errorf("the kill wasn't rewarded: %+v", progress)
+	}
+
+	world.Commands.Undo(world)
+	if world.Player.Progress != (Progress{Level: 1, Experience: LevelThreshold(2) - 1}) {
+		t.Errorf("undo didn't take the experience back: %+v", world.Player.Progress)
+	}
+}
+
+func TestRaiseStatCommand(t *testing.T) {
+	world := newCommandTestWorld(t)
+	player := world.Player
+	if world.Execute(NewRaiseStatCommand(world)) == nil {
+		t.Error("raised a stat without points")
+	}
+
+	player.Progress.Points = 2
+	player.Progress.Selected = 2
+	health, vitality := player.Health, player.Stats.Vitality
+	if err := world.Execute(NewRaiseStatCommand(world)); err != nil {
+		t.Fatal(err)
+	}
+	if player.Stats.Vitality != vitality+1 || player.Progress.Points != 1 {
+		t.Errorf("vitality %d with %d points left", player.Stats.Vitality, player.Progress.Points)
+	}
+	if player.Health != health+PLAYER_HEALTH_MULT {
+		t.Errorf("health %v didn't go up with the max health", player.Health)
+	}
+
+	world.Commands.Undo(world)
+	if player.Stats.Vitality != vitality || player.Progress.Points != 2 || player.Health != health {
+		t.Error("undo didn't take the point back")
+	}
+
+	player.Progress.SelectStat(1)
+	player.Stats.Movement = MAX_LEVELLED_STAT
+	if world.Execute(NewRaiseStatCommand(world)) == nil {
+		t.Error("raised movement past the limit")
+	}
+	player.Progress.SelectStat(-4)
+	if player.Progress.Selected != 4 {
+		t.Errorf("selection didn't wrap around, at %d", player.Progress.Selected)
+	}
+}
@@ -0,0 +1,209 @@
+package sim
+
+import (
+	"fov"
+	"log"
+	"math/rand"
+	"time"
+)
+
+const PLAYER_OFFSET_X int32 = 0
+const PLAYER_OFFSET_Y int32 = 0
+
+// Logs the generated maps and the executed and rejected commands
+var DebugMode bool
+
+func debugPrint(v interface{}) {
+	if DebugMode {
+		log.Print(v)
+	}
+}
+
+// Config is what a new game is started from
+type Config struct {
+	// 0 picks a random seed
+	Seed         int64
+	LevelPath    string
+	Generator    string
+	MapOverrides map[string]string
+	// Items, enemies and abilities of the game, nil for DefaultData
+	Data *Data
+}
+
+// World is a whole game, any number of them can be played side by side
+type World struct {
+	Player    *Player
+	Map       [][]*Tile
+	Enemies   []*Enemy
+	Items     []*FloorItem
+	Dungeon   *Dungeon
+	Data      *Data
+	Selection SelectionMode
+	Seed      int64
+	TileSet   string
+	Kills     int
+	Turns     TurnScheduler
+	Commands  CommandLog
+	Events    EventLog
+	// Lit by the player on the current floor
+	Flares []*Flare
+
+	PlayerSight fov.Set
+	// Updated every Step: the tiles the player sees or remembers and the enemies and items they see
+	KnownTiles     []*Tile
+	VisibleEnemies []*Enemy
+	VisibleItems   []*FloorItem
+
+	// Counts the changes to the tiles, enemies see what has changed once it goes up
+	tileRevision int
+
+	// Used for everything random after the floors have been generated
+	rng       *rand.Rand
+	rngSource *RandomSource
+}
+
+// The cursor used to pick targets for digging, attacking and abilities
+type SelectionMode struct {
+	Using bool
+	Pos   IVector2
+}
+
+func NewWorld(config Config) *World {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	data := config.Data
+	if data == nil {
+		data = DefaultData()
+	}
+
+	player := NewPlayer()
+	world := World{
+		Player:    player,
+		Map:       nil,
+		Data:      data,
+		Selection: SelectionMode{Pos: player.Pos},
+		Seed:      seed,
+	}
+	world.restoreRng(seed, 0)
+
+	level := &LevelFile{
+		Name:      "generated",
+		Generator: config.Generator,
+		Config:    DefaultGeneratorConfig(),
+	}
+	if config.LevelPath != "" {
+		if l, err := LoadLevelFile(config.LevelPath, data); err == nil {
+			level = l
+			world.TileSet = level.TileSet
+		} else {
+			log.Println("Couldn't load level, generating one instead: ", err)
+		}
+	}
+	if level.MapString == "" {
+		level.Config = level.Config.WithOverrides(config.MapOverrides)
+	}
+
+	world.Dungeon = NewDungeon(seed, level, data)
+	floor := world.Dungeon.Current()
+	world.enterFloor(floor, floor.Entry)
+
+	return &world
+}
+
+func NewPlayer() *Player {
+	player := Player{
+		Pos: IVector2{X: PLAYER_OFFSET_X, Y: PLAYER_OFFSET_Y},
+		Stats: Stats{
+			Movement:   6,
+			Visibility: 8,
+			Vitality:   6,
+			Strength:   6,
+			Dexterity:  6,
+		},
+		Turn: TurnData{
+			Movement: 6,
+			Actions:  3,
+			Done:     false,
+			Energy:   TURN_ENERGY,
+		},
+		Progress:  Progress{Level: 1},
+		Abilities: AbilityBar{Cooldowns: make(map[string]int)},
+	}
+	player.Health = player.MaxHealth()
+
+	return &player
+}
+
+// Step runs a frame of the simulation with the input from the front-end
+// and then hands it everything that happened during the frame
+func (world *World) Step(frontend Frontend) {
+	world.update(frontend.Poll())
+	for _, event := range world.Events.Flush() {
+		frontend.HandleEvent(event)
+	}
+}
+
+func (world *World) update(input Input) {
+	world.HandleControls(input)
+	world.updatePlayerSight()
+
+	if world.Player.Turn.Done {
+		world.Turns.RunEnemyPhase()
+	}
+
+	// Several enemies can die in the same frame
+	alive := make([]*Enemy, 0, len(world.Enemies))
+	for _, enemy := range world.Enemies {
+		if enemy.Health <= 0.0 {
+			world.Kills++
+			continue
+		}
+		alive = append(alive, enemy)
+	}
+	world.Enemies = alive
+
+	world.VisibleEnemies = nil
+	for _, enemy := range world.Enemies {
+		if enemy.VisibleToPlayer(world) {
+			enemy.LightLevel = calculateLightLevel(enemy.DistanceToPlayer(world), world.Player.EffectiveStats().Visibility)
+			if light := world.flareLight(enemy.Pos); light > enemy.LightLevel {
+				enemy.LightLevel = light
+			}
+			enemy.sight(world)
+			world.VisibleEnemies = append(world.VisibleEnemies, enemy)
+		}
+	}
+
+	world.VisibleItems = nil
+	for _, item := range world.Items {
+		if tile, ok := world.GetMapTile(item.Pos); ok && tile.VisibleToPlayer(world) {
+			world.VisibleItems = append(world.VisibleItems, item)
+		}
+	}
+
+	//*
+	//*	Filter out the tiles that are visible to the player
+	//*	If the the tile is visible push it to a separate array
+	//*	that the renderer can use to save time not going through all this at render time
+	//*	Tiles seen before are drawn too, from memory
+	//*
+	world.KnownTiles = nil
+
+	for _, tile_row := range world.Map {
+		for _, tile := range tile_row {
+			if tile != nil {
+				//! Check if tile coordinates are in the player's visibility range
+				//! If not, only draw it if it has been explored
+				if tile.VisibleToPlayer(world) {
+					tile.Remember()
+					world.KnownTiles = append(world.KnownTiles, tile)
+				} else if tile.Explored {
+					world.KnownTiles = append(world.KnownTiles, tile)
+				}
+			}
+		}
+	}
+}
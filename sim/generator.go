@@ -0,0 +1,443 @@
+package sim
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	simplex "github.com/ojrac/opensimplex-go"
+)
+
+const DEFAULT_GENERATOR = "cave"
+const MAX_MAP_DIMENSIONS = 1000
+
+// Generator builds a map string out of the glyphs understood by charToTile
+type Generator interface {
+	Generate(config GeneratorConfig, rng *rand.Rand) string
+}
+
+// GeneratorConfig can be set from a level file header or from the command line,
+// both use the keys listed in GeneratorConfigKeys
+type GeneratorConfig struct {
+	Width             int
+	Height            int
+	NoiseScale        float64
+	ObstacleThreshold float64
+	WallThreshold     float64
+	FloorThreshold    float64
+	SpawnRate         float64
+}
+
+var GeneratorConfigKeys = []string{
+	"size",
+	"noise-scale",
+	"obstacle-threshold",
+	"wall-threshold",
+	"floor-threshold",
+	"spawn-rate",
+}
+
+func DefaultGeneratorConfig() GeneratorConfig {
+	return GeneratorConfig{
+		Width:             101,
+		Height:            101,
+		NoiseScale:        0.1,
+		ObstacleThreshold: 0.7,
+		WallThreshold:     0.1,
+		FloorThreshold:    -0.6,
+		SpawnRate:         0.01,
+	}
+}
+
+func (config *GeneratorConfig) Set(key string, value string) error {
+	if key == "size" {
+		w, h, err := parseMapSize(value)
+		if err != nil {
+			return err
+		}
+		config.Width = w
+		config.Height = h
+		return nil
+	}
+
+	var field *float64
+	switch key {
+	case "noise-scale":
+		field = &config.NoiseScale
+	case "obstacle-threshold":
+		field = &config.ObstacleThreshold
+	case "wall-threshold":
+		field = &config.WallThreshold
+	case "floor-threshold":
+		field = &config.FloorThreshold
+	case "spawn-rate":
+		field = &config.SpawnRate
+	default:
+		return fmt.Errorf("unknown generator setting %q", key)
+	}
+
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("%v should be a number, got %q", key, value)
+	}
+	*field = v
+	return nil
+}
+
+// WithOverrides applies settings given on the command line, an invalid result keeps the original config
+func (config GeneratorConfig) WithOverrides(overrides map[string]string) GeneratorConfig {
+	next := config
+	for _, key := range GeneratorConfigKeys {
+		if value, ok := overrides[key]; ok {
+			if err := next.Set(key, value); err != nil {
+				log.Println("Ignoring map setting: ", err)
+			}
+		}
+	}
+
+	if err := next.Validate(); err != nil {
+		log.Println("Ignoring map settings: ", err)
+		return config
+	}
+	return next
+}
+
+func (config GeneratorConfig) Validate() error {
+	if config.Width < 3 || config.Height < 3 || config.Width > MAX_MAP_DIMENSIONS || config.Height > MAX_MAP_DIMENSIONS {
+		return fmt.Errorf("map size must be between 3x3 and %dx%d, got %dx%d", MAX_MAP_DIMENSIONS, MAX_MAP_DIMENSIONS, config.Width, config.Height)
+	}
+	if config.NoiseScale <= 0.0 {
+		return fmt.Errorf("noise-scale must be positive, got %v", config.NoiseScale)
+	}
+	if !(config.FloorThreshold < config.WallThreshold && config.WallThreshold < config.ObstacleThreshold) {
+		return fmt.Errorf("thresholds must satisfy floor < wall < obstacle, got %v < %v < %v", config.FloorThreshold, config.WallThreshold, config.ObstacleThreshold)
+	}
+	if config.SpawnRate < 0.0 || config.SpawnRate > 1.0 {
+		return fmt.Errorf("spawn-rate must be between 0 and 1, got %v", config.SpawnRate)
+	}
+	return nil
+}
+
+func parseMapSize(value string) (int, int, error) {
+	split := strings.Split(strings.ToLower(value), "x")
+	if len(split) != 2 {
+		return 0, 0, fmt.Errorf("size should be WIDTHxHEIGHT, got %q", value)
+	}
+
+	w, errW := strconv.Atoi(strings.TrimSpace(split[0]))
+	h, errH := strconv.Atoi(strings.TrimSpace(split[1]))
+	if errW != nil || errH != nil {
+		return 0, 0, fmt.Errorf("size should be WIDTHxHEIGHT, got %q", value)
+	}
+
+	if w < 1 || h < 1 || w > MAX_MAP_DIMENSIONS || h > MAX_MAP_DIMENSIONS {
+		return 0, 0, fmt.Errorf("size must be between 1x1 and %dx%d", MAX_MAP_DIMENSIONS, MAX_MAP_DIMENSIONS)
+	}
+
+	return w, h, nil
+}
+
+var generators = map[string]func() Generator{
+	"cave": func() Generator { return CaveGenerator{} },
+	"bsp":  func() Generator { return NewBSPGenerator() },
+}
+
+func GetGenerator(name string) (Generator, bool) {
+	if name == "" {
+		name = DEFAULT_GENERATOR
+	}
+	if newGenerator, ok := generators[strings.ToLower(name)]; ok {
+		return newGenerator(), true
+	}
+	return nil, false
+}
+
+func GeneratorNames() []string {
+	var names []string
+	for name := range generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type glyphGrid [][]byte
+
+func newGlyphGrid(width int, height int, fill byte) glyphGrid {
+	grid := make(glyphGrid, height)
+	for y := range grid {
+		grid[y] = make([]byte, width)
+		for x := range grid[y] {
+			grid[y][x] = fill
+		}
+	}
+	return grid
+}
+
+func (grid glyphGrid) String() string {
+	var builder strings.Builder
+	for _, row := range grid {
+		builder.Write(row)
+		builder.WriteByte('\n')
+	}
+	return builder.String()
+}
+
+//*
+//*	Simplex noise caves
+//*
+
+type CaveGenerator struct{}
+
+func (generator CaveGenerator) Generate(config GeneratorConfig, rng *rand.Rand) string {
+	t := time.Now()
+	log.Println("Map generation started")
+	noise := simplex.New(rng.Int63())
+	grid := newGlyphGrid(config.Width, config.Height, '@')
+
+	// The outermost ring stays as wall
+	for y := 1; y < config.Height-1; y++ {
+		for x := 1; x < config.Width-1; x++ {
+			val := noise.Eval2(float64(y)*config.NoiseScale, float64(x)*config.NoiseScale)
+			if val > config.ObstacleThreshold || val < -config.ObstacleThreshold {
+				grid[y][x] = '-'
+			} else if val > config.WallThreshold {
+				grid[y][x] = '@'
+			} else if val > config.FloorThreshold {
+				if rng.Float64() < config.SpawnRate {
+					grid[y][x] = 'P'
+				} else {
+					grid[y][x] = '_'
+				}
+			} else {
+				grid[y][x] = '!'
+			}
+		}
+	}
+
+	log.Println("Map generation finished in ", time.Since(t))
+	return grid.String()
+}
+
+//*
+//*	Binary space partitioning rooms and corridors
+//*
+
+type BSPGenerator struct {
+	MinLeafSize int
+	MinRoomSize int
+	MaxDepth    int
+	RubbleRate  float32
+	MossRate    float32
+}
+
+type bspRect struct {
+	X, Y, W, H int
+}
+
+func (rect bspRect) center() (int, int) {
+	return rect.X + rect.W/2, rect.Y + rect.H/2
+}
+
+type bspNode struct {
+	Area  bspRect
+	Room  *bspRect
+	Left  *bspNode
+	Right *bspNode
+}
+
+func NewBSPGenerator() BSPGenerator {
+	return BSPGenerator{
+		MinLeafSize: 10,
+		MinRoomSize: 4,
+		MaxDepth:    6,
+		RubbleRate:  0.02,
+		MossRate:    0.1,
+	}
+}
+
+func (generator BSPGenerator) Generate(config GeneratorConfig, rng *rand.Rand) string {
+	t := time.Now()
+	log.Println("BSP map generation started")
+	grid := newGlyphGrid(config.Width, config.Height, '@')
+
+	// Keep the outermost ring as wall
+	root := &bspNode{Area: bspRect{X: 1, Y: 1, W: config.Width - 2, H: config.Height - 2}}
+	generator.split(root, 0, rng)
+
+	var rooms []bspRect
+	generator.carveRooms(root, grid, rng, &rooms)
+	generator.connect(root, grid, rng)
+
+	for _, room := range rooms {
+		// Roughly the same spawn density as the caves get from the spawn rate
+		spawns := 1 + int(float64(room.W*room.H)*config.SpawnRate*rng.Float64()*2.0)
+		for i := 0; i < spawns; i++ {
+			x := room.X + rng.Intn(room.W)
+			y := room.Y + rng.Intn(room.H)
+			grid[y][x] = 'P'
+		}
+	}
+
+	generator.decorate(grid, rng)
+
+	log.Printf("BSP map with %d rooms generated in %v", len(rooms), time.Since(t))
+	return grid.String()
+}
+
+func (generator BSPGenerator) split(node *bspNode, depth int, rng *rand.Rand) {
+	if depth >= generator.MaxDepth {
+		return
+	}
+
+	area := node.Area
+	canSplitH := area.H >= generator.MinLeafSize*2
+	canSplitV := area.W >= generator.MinLeafSize*2
+	if !canSplitH && !canSplitV {
+		return
+	}
+
+	horizontal := canSplitH
+	if canSplitH && canSplitV {
+		horizontal = rng.Intn(2) == 0
+		if float32(area.W)/float32(area.H) >= 1.25 {
+			horizontal = false
+		} else if float32(area.H)/float32(area.W) >= 1.25 {
+			horizontal = true
+		}
+	}
+
+	if horizontal {
+		at := generator.MinLeafSize + rng.Intn(area.H-generator.MinLeafSize*2+1)
+		node.Left = &bspNode{Area: bspRect{X: area.X, Y: area.Y, W: area.W, H: at}}
+		node.Right = &bspNode{Area: bspRect{X: area.X, Y: area.Y + at, W: area.W, H: area.H - at}}
+	} else {
+		at := generator.MinLeafSize + rng.Intn(area.W-generator.MinLeafSize*2+1)
+		node.Left = &bspNode{Area: bspRect{X: area.X, Y: area.Y, W: at, H: area.H}}
+		node.Right = &bspNode{Area: bspRect{X: area.X + at, Y: area.Y, W: area.W - at, H: area.H}}
+	}
+
+	generator.split(node.Left, depth+1, rng)
+	generator.split(node.Right, depth+1, rng)
+}
+
+func (generator BSPGenerator) carveRooms(node *bspNode, grid glyphGrid, rng *rand.Rand, rooms *[]bspRect) {
+	if node.Left != nil || node.Right != nil {
+		generator.carveRooms(node.Left, grid, rng, rooms)
+		generator.carveRooms(node.Right, grid, rng, rooms)
+		return
+	}
+
+	// Leave a wall between the room and the edges of its leaf
+	maxW := node.Area.W - 2
+	maxH := node.Area.H - 2
+	if maxW < generator.MinRoomSize || maxH < generator.MinRoomSize {
+		return
+	}
+
+	w := generator.MinRoomSize + rng.Intn(maxW-generator.MinRoomSize+1)
+	h := generator.MinRoomSize + rng.Intn(maxH-generator.MinRoomSize+1)
+	room := bspRect{
+		X: node.Area.X + 1 + rng.Intn(maxW-w+1),
+		Y: node.Area.Y + 1 + rng.Intn(maxH-h+1),
+		W: w,
+		H: h,
+	}
+
+	for y := room.Y; y < room.Y+room.H; y++ {
+		for x := room.X; x < room.X+room.W; x++ {
+			grid[y][x] = '_'
+		}
+	}
+
+	node.Room = &room
+	*rooms = append(*rooms, room)
+}
+
+// Joins the two halves of every split with a corridor, returns a room of the subtree to connect to
+func (generator BSPGenerator) connect(node *bspNode, grid glyphGrid, rng *rand.Rand) *bspRect {
+	if node.Left == nil && node.Right == nil {
+		return node.Room
+	}
+
+	left := generator.connect(node.Left, grid, rng)
+	right := generator.connect(node.Right, grid, rng)
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+
+	x1, y1 := left.center()
+	x2, y2 := right.center()
+	carveCorridor(grid, x1, y1, x2, y2, rng.Intn(2) == 0)
+
+	if rng.Intn(2) == 0 {
+		return left
+	}
+	return right
+}
+
+func (generator BSPGenerator) decorate(grid glyphGrid, rng *rand.Rand) {
+	height := len(grid)
+	for y, row := range grid {
+		width := len(row)
+		for x, glyph := range row {
+			switch glyph {
+			case '_':
+				if rng.Float32() < generator.RubbleRate {
+					grid[y][x] = '-'
+				}
+			case '@':
+				if x == 0 || y == 0 || x == width-1 || y == height-1 {
+					continue
+				}
+				if glyphGridTouchesFloor(grid, x, y) && rng.Float32() < generator.MossRate {
+					grid[y][x] = '!'
+				}
+			}
+		}
+	}
+}
+
+// L-shaped corridor, horizontal leg first if hFirst is set
+func carveCorridor(grid glyphGrid, x1 int, y1 int, x2 int, y2 int, hFirst bool) {
+	carve := func(x int, y int) {
+		if grid[y][x] == '@' || grid[y][x] == '!' {
+			grid[y][x] = '_'
+		}
+	}
+
+	cornerX, cornerY := x2, y1
+	if !hFirst {
+		cornerX, cornerY = x1, y2
+	}
+
+	for _, leg := range [][4]int{{x1, y1, cornerX, cornerY}, {cornerX, cornerY, x2, y2}} {
+		x, y := leg[0], leg[1]
+		carve(x, y)
+		for x != leg[2] || y != leg[3] {
+			x += sign(leg[2] - x)
+			y += sign(leg[3] - y)
+			carve(x, y)
+		}
+	}
+}
+
+func glyphGridTouchesFloor(grid glyphGrid, x int, y int) bool {
+	for _, d := range [][2]int{{0, -1}, {1, 0}, {0, 1}, {-1, 0}} {
+		nx, ny := x+d[0], y+d[1]
+		if ny < 0 || ny >= len(grid) || nx < 0 || nx >= len(grid[ny]) {
+			continue
+		}
+		if glyph := grid[ny][nx]; glyph != '@' && glyph != '!' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package sim
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+)
+
+// Frames the auto player gets per round before the simulation gives up on it
+const MAX_FRAMES_PER_ROUND = 256
+
+// Headless is a front-end without a window, it plays the input it is given and keeps count of what happened
+type Headless struct {
+	Input InputSource
+	Stats RunStats
+}
+
+type RunStats struct {
+	Frames    int
+	Rounds    int
+	Depth     int
+	Kills     int
+	Level     int
+	Health    float32
+	Died      bool
+	Moves     int
+	Digs      int
+	Attacks   int
+	Misses    int
+	Hits      int
+	Dodges    int
+	Abilities int
+	Undos     int
+	Duration  time.Duration
+}
+
+func (headless *Headless) Poll() Input {
+	headless.Stats.Frames++
+	return headless.Input.Poll()
+}
+
+func (headless *Headless) HandleEvent(event Event) {
+	switch event.Kind {
+	case EVENT_MOVED:
+		headless.Stats.Moves++
+	case EVENT_DUG:
+		headless.Stats.Digs++
+	case EVENT_ATTACKED:
+		headless.Stats.Attacks++
+	case EVENT_MISSED:
+		headless.Stats.Attacks++
+		headless.Stats.Misses++
+	case EVENT_PLAYER_HIT:
+		headless.Stats.Hits++
+	case EVENT_DODGED:
+		headless.Stats.Dodges++
+	case EVENT_USED_ABILITY:
+		headless.Stats.Abilities++
+	case EVENT_UNDONE:
+		headless.Stats.Undos++
+	case EVENT_PLAYER_DIED:
+		headless.Stats.Died = true
+	}
+}
+
+func (stats RunStats) String() string {
+	return fmt.Sprintf(
+		"rounds: %d\nfloor: %d\nkills: %d\nlevel: %d\nhealth: %.1f\ndied: %v\nmoves: %d\ndigs: %d\nattacks: %d\nmisses: %d\nhits taken: %d\ndodged: %d\nabilities used: %d\nundos: %d\nframes: %d\ntime: %v\n",
+		stats.Rounds, stats.Depth+1, stats.Kills, stats.Level, stats.Health, stats.Died, stats.Moves, stats.Digs,
+		stats.Attacks, stats.Misses, stats.Hits, stats.Dodges, stats.Abilities, stats.Undos, stats.Frames, stats.Duration,
+	)
+}
+
+// Simulate plays the given number of rounds with an AutoPlayer, or until it dies
+func Simulate(config Config, rounds int) RunStats {
+	t := time.Now()
+	world := NewWorld(config)
+	frontend := &Headless{Input: NewAutoPlayer(world)}
+
+	for frame := 0; world.Turns.Round < rounds && !world.Player.IsDead(); frame++ {
+		if frame > rounds*MAX_FRAMES_PER_ROUND {
+			log.Printf("Auto player got stuck on round %d", world.Turns.Round)
+			break
+		}
+		world.Step(frontend)
+	}
+
+	stats := frontend.Stats
+	stats.Rounds = world.Turns.Round
+	stats.Depth = world.Dungeon.Depth
+	stats.Kills = world.Kills
+	stats.Level = world.Player.Progress.Level
+	stats.Health = world.Player.Health
+	stats.Duration = time.Since(t)
+	return stats
+}
+
+// AutoPlayer presses buttons like a player would, fighting enemies next to it and wandering around otherwise.
+// It has a random source of its own so that it doesn't change what the game rolls.
+type AutoPlayer struct {
+	world *World
+	rng   *rand.Rand
+	// Inputs planned for the next frames
+	queue []Input
+	round int
+	tries int
+}
+
+// The auto player plays the given world, its own random numbers come from the world's seed
+func NewAutoPlayer(world *World) *AutoPlayer {
+	return &AutoPlayer{world: world, rng: rand.New(rand.NewSource(world.Seed))}
+}
+
+var autoPlayerDirections = []Input{INPUT_LEFT, INPUT_RIGHT, INPUT_UP, INPUT_DOWN}
+
+func (auto *AutoPlayer) Poll() Input {
+	if len(auto.queue) > 0 {
+		input := auto.queue[0]
+		auto.queue = auto.queue[1:]
+		return input
+	}
+
+	world := auto.world
+	player := world.Player
+	if player.Turn.Done {
+		return 0
+	}
+	// Points go round the stats one at a time
+	if player.Progress.Points > 0 {
+		auto.queue = []Input{INPUT_NEXT_STAT}
+		return INPUT_RAISE_STAT
+	}
+	if auto.round != world.Turns.Round {
+		auto.round = world.Turns.Round
+		auto.tries = 0
+	}
+
+	if player.Turn.Actions > 0 {
+		for _, enemy := range world.Enemies {
+			if enemy.Health > 0.0 && world.withinReach(enemy.Pos) {
+				auto.queue = []Input{inputTowards(player.Pos, enemy.Pos), INPUT_ATTACK, INPUT_SELECT}
+				return INPUT_SELECT
+			}
+		}
+	}
+
+	// Blocked moves don't use up movement, so give up on moving after a while
+	auto.tries++
+	if player.Turn.Movement > 0 && auto.tries <= int(player.Stats.Movement)*2 {
+		if len(world.VisibleEnemies) > 0 {
+			return inputTowards(player.Pos, world.VisibleEnemies[0].Pos)
+		}
+		return autoPlayerDirections[auto.rng.Intn(len(autoPlayerDirections))]
+	}
+	return INPUT_END_TURN
+}
+
+// Directions to press to step from one position towards another
+func inputTowards(from IVector2, to IVector2) Input {
+	var input Input
+	switch sign(int(to.X - from.X)) {
+	case -1:
+		input |= INPUT_LEFT
+	case 1:
+		input |= INPUT_RIGHT
+	}
+	switch sign(int(to.Y - from.Y)) {
+	case -1:
+		input |= INPUT_UP
+	case 1:
+		input |= INPUT_DOWN
+	}
+	return input
+}
@@ -0,0 +1,77 @@
+package sim
+
+import (
+	"io/ioutil"
+	"log"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	out := log.Writer()
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(out)
+
+	config := Config{Seed: 99, Generator: "bsp", MapOverrides: map[string]string{"size": "48x32"}}
+	first := Simulate(config, 20)
+	if first.Rounds < 20 && !first.Died {
+		t.Fatalf("stopped on round %d without dying", first.Rounds)
+	}
+	if first.Moves == 0 {
+		t.Error("the auto player never moved")
+	}
+
+	second := Simulate(config, 20)
+	first.Duration, second.Duration = 0, 0
+	if first != second {
+		t.Errorf("runs with the same seed differ:\n%v\n%v", first, second)
+	}
+}
+
+func TestWorldsSideBySide(t *testing.T) {
+	out := log.Writer()
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(out)
+
+	play := func(worlds ...*World) {
+		frontends := make([]*Headless, len(worlds))
+		for i, world := range worlds {
+			frontends[i] = &Headless{Input: NewAutoPlayer(world)}
+		}
+		for frame := 0; frame < 300; frame++ {
+			for i, world := range worlds {
+				world.Step(frontends[i])
+			}
+		}
+	}
+
+	config := Config{Seed: 99, Generator: "bsp", MapOverrides: map[string]string{"size": "48x32"}}
+	alone := NewWorld(config)
+	play(alone)
+
+	// Another world played in between must not change anything in the first one
+	first := NewWorld(config)
+	other := NewWorld(Config{Seed: 5, Generator: "cave", MapOverrides: map[string]string{"size": "40x30"}})
+	play(first, other)
+
+	if first.StateHash() != alone.StateHash() {
+		t.Error("the world played alongside another ended up different")
+	}
+	if other.StateHash() == first.StateHash() {
+		t.Error("worlds with different seeds ended up the same")
+	}
+}
+
+func TestInputTowards(t *testing.T) {
+	from := NewIVector2(2*TILE_SIZE, 2*TILE_SIZE)
+	cases := map[IVector2]Input{
+		NewIVector2(1*TILE_SIZE, 2*TILE_SIZE): INPUT_LEFT,
+		NewIVector2(3*TILE_SIZE, 1*TILE_SIZE): INPUT_RIGHT | INPUT_UP,
+		NewIVector2(2*TILE_SIZE, 5*TILE_SIZE): INPUT_DOWN,
+		from:                                  0,
+	}
+	for to, expected := range cases {
+		if input := inputTowards(from, to); input != expected {
+			t.Errorf("towards %v expected %q, got %q", to, expected, input)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package sim
+
+import (
+	"strings"
+)
+
+// Input holds every action pressed during a frame as bit flags
+type Input uint32
+
+const (
+	INPUT_LEFT            Input = 1 << iota
+	INPUT_RIGHT           Input = 1 << iota
+	INPUT_UP              Input = 1 << iota
+	INPUT_DOWN            Input = 1 << iota
+	INPUT_SELECT          Input = 1 << iota
+	INPUT_DIG             Input = 1 << iota
+	INPUT_ATTACK          Input = 1 << iota
+	INPUT_STAIRS          Input = 1 << iota
+	INPUT_END_TURN        Input = 1 << iota
+	INPUT_CHARACTER_PANEL Input = 1 << iota
+	INPUT_PAUSE           Input = 1 << iota
+	INPUT_QUICKSAVE       Input = 1 << iota
+	INPUT_QUICKLOAD       Input = 1 << iota
+	INPUT_DEBUG_DISPLAY   Input = 1 << iota
+	INPUT_VISIBILITY_UP   Input = 1 << iota
+	INPUT_VISIBILITY_DOWN Input = 1 << iota
+	INPUT_UNDO            Input = 1 << iota
+	INPUT_PICK_UP         Input = 1 << iota
+	INPUT_DROP            Input = 1 << iota
+	INPUT_USE_ITEM        Input = 1 << iota
+	INPUT_NEXT_ITEM       Input = 1 << iota
+	INPUT_PREVIOUS_ITEM   Input = 1 << iota
+	INPUT_INVENTORY_PANEL Input = 1 << iota
+	INPUT_EQUIP           Input = 1 << iota
+	INPUT_NEXT_STAT       Input = 1 << iota
+	INPUT_RAISE_STAT      Input = 1 << iota
+	INPUT_NEXT_ABILITY    Input = 1 << iota
+	INPUT_USE_ABILITY     Input = 1 << iota
+)
+
+type inputAction struct {
+	Action Input
+	Name   string
+	// Actions that only touch the window or files outside of the game are left out of replays,
+	// and so are the debug ones, a replay plays the same with or without -debug
+	Recorded bool
+}
+
+var inputActions = []inputAction{
+	{INPUT_LEFT, "left", true},
+	{INPUT_RIGHT, "right", true},
+	{INPUT_UP, "up", true},
+	{INPUT_DOWN, "down", true},
+	{INPUT_SELECT, "select", true},
+	{INPUT_DIG, "dig", true},
+	{INPUT_ATTACK, "attack", true},
+	{INPUT_STAIRS, "stairs", true},
+	{INPUT_END_TURN, "end-turn", true},
+	{INPUT_CHARACTER_PANEL, "character-panel", true},
+	{INPUT_PAUSE, "pause", false},
+	{INPUT_QUICKSAVE, "quicksave", false},
+	{INPUT_QUICKLOAD, "quickload", false},
+	{INPUT_DEBUG_DISPLAY, "debug-display", false},
+	{INPUT_VISIBILITY_UP, "visibility-up", false},
+	{INPUT_VISIBILITY_DOWN, "visibility-down", false},
+	{INPUT_UNDO, "undo", true},
+	{INPUT_PICK_UP, "pick-up", true},
+	{INPUT_DROP, "drop", true},
+	{INPUT_USE_ITEM, "use-item", true},
+	{INPUT_NEXT_ITEM, "next-item", true},
+	{INPUT_PREVIOUS_ITEM, "previous-item", true},
+	{INPUT_INVENTORY_PANEL, "inventory-panel", true},
+	{INPUT_EQUIP, "equip", true},
+	{INPUT_NEXT_STAT, "next-stat", true},
+	{INPUT_RAISE_STAT, "raise-stat", true},
+	{INPUT_NEXT_ABILITY, "next-ability", true},
+	{INPUT_USE_ABILITY, "use-ability", true},
+}
+
+func (input Input) Has(action Input) bool {
+	return input&action != 0
+}
+
+// Recorded drops the actions that don't belong in a replay
+func (input Input) Recorded() Input {
+	var recorded Input
+	for _, action := range inputActions {
+		if action.Recorded && input.Has(action.Action) {
+			recorded |= action.Action
+		}
+	}
+	return recorded
+}
+
+// String lists the names of the actions separated by spaces, the format used in replay files
+func (input Input) String() string {
+	var names []string
+	for _, action := range inputActions {
+		if input.Has(action.Action) {
+			names = append(names, action.Name)
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+func ParseInput(s string) (Input, bool) {
+	var input Input
+	for _, name := range strings.Fields(s) {
+		found := false
+		for _, action := range inputActions {
+			if action.Name == name {
+				input |= action.Action
+				found = true
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return input, true
+}
+
+// InputSource gives the game the actions for the current frame
+type InputSource interface {
+	Poll() Input
+}
+
+// Frontend shows the game to the player and feeds it their input,
+// the simulation never draws anything or reads devices by itself. See Step.
+type Frontend interface {
+	InputSource
+	HandleEvent(event Event)
+}
@@ -0,0 +1,324 @@
+package sim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"time"
+)
+
+const ITEMS_FILE = DATA_FOLDER + "items.json"
+
+type ItemCategory string
+
+const (
+	ITEM_WEAPON       ItemCategory = "weapon"
+	ITEM_ARMOUR       ItemCategory = "armour"
+	ITEM_CONSUMABLE   ItemCategory = "consumable"
+	ITEM_KEY          ItemCategory = "key"
+	ITEM_DIGGING_TOOL ItemCategory = "digging tool"
+	ITEM_TRINKET      ItemCategory = "trinket"
+)
+
+var itemCategories = []ItemCategory{ITEM_WEAPON, ITEM_ARMOUR, ITEM_CONSUMABLE, ITEM_KEY, ITEM_DIGGING_TOOL, ITEM_TRINKET}
+
+// Chance of a spawn point getting an item on generated floors, enemies are placed first
+const ITEM_SPAWN_RATE = 0.08
+
+// The player can carry this many items, however light they are
+const INVENTORY_SLOTS = 10
+
+// The weight the player can carry is CARRY_WEIGHT plus CARRY_WEIGHT_PER_STRENGTH for every point of Strength
+const CARRY_WEIGHT = 10.0
+const CARRY_WEIGHT_PER_STRENGTH = 2.0
+
+type Item struct {
+	Name     string       `json:"name"`
+	Category ItemCategory `json:"category"`
+	Weight   float32      `json:"weight"`
+	// Health restored by using a consumable
+	Heal float32 `json:"heal,omitempty"`
+
+	// Only weapons, armour and trinkets can be equipped, see equipmentSlots
+	Equipped bool      `json:"equipped,omitempty"`
+	Bonus    StatBonus `json:"bonus"`
+	// Rolled on top of the wearer's damage on every hit
+	Damage Dice `json:"damage"`
+	// Taken off every hit the wearer takes
+	Armour float32 `json:"armour,omitempty"`
+	// Inflicted on whoever the wearer hits, or put on whoever uses a consumable
+	Effects []Infliction `json:"effects,omitempty"`
+}
+
+// ItemKind is an item as described in the items file, along with where it can be found
+type ItemKind struct {
+	Item
+	// Relative chance of being picked among the items that can appear on a floor, see Archetype
+	SpawnWeight    float64 `json:"spawnWeight"`
+	WeightPerDepth float64 `json:"weightPerDepth"`
+	MinDepth       int     `json:"minDepth"`
+	// 0 for no limit
+	MaxDepth int `json:"maxDepth"`
+}
+
+// An item lying on the floor
+type FloorItem struct {
+	Pos  IVector2 `json:"pos"`
+	Item *Item    `json:"item"`
+}
+
+type itemsFile struct {
+	Items []*ItemKind `json:"items"`
+}
+
+// LoadItems replaces the items with the ones in the file
+func (data *Data) LoadItems(path string) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	loaded, err := ParseItems(path, contents)
+	if err != nil {
+		return err
+	}
+	data.Items = loaded
+	log.Printf("Loaded %d items from %v", len(loaded), path)
+	return nil
+}
+
+func ParseItems(path string, data []byte) ([]*ItemKind, error) {
+	var file itemsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	if len(file.Items) == 0 {
+		return nil, fmt.Errorf("%v: no items", path)
+	}
+
+	names := make(map[string]bool)
+	for i, kind := range file.Items {
+		if err := kind.validate(); err != nil {
+			return nil, fmt.Errorf("%v: item %d: %v", path, i+1, err)
+		}
+		if names[kind.Name] {
+			return nil, fmt.Errorf("%v: item %d: %q is defined twice", path, i+1, kind.Name)
+		}
+		names[kind.Name] = true
+	}
+	return file.Items, nil
+}
+
+func (kind *ItemKind) validate() error {
+	switch {
+	case kind.Name == "":
+		return fmt.Errorf("missing name")
+	case !validCategory(kind.Category):
+		return fmt.Errorf("%v has unknown category %q, expected one of %v", kind.Name, kind.Category, itemCategories)
+	case kind.Weight < 0:
+		return fmt.Errorf("%v has a negative weight", kind.Name)
+	case kind.Heal < 0 || (kind.Heal > 0 && kind.Category != ITEM_CONSUMABLE):
+		return fmt.Errorf("%v can't heal", kind.Name)
+	case kind.Armour < 0:
+		return fmt.Errorf("%v has negative armour", kind.Name)
+	case !kind.Equippable() && (kind.Bonus != StatBonus{} || !kind.Damage.IsZero() || kind.Armour != 0):
+		return fmt.Errorf("%v has bonuses but can't be equipped", kind.Name)
+	case len(kind.Effects) > 0 && !kind.Equippable() && kind.Category != ITEM_CONSUMABLE:
+		return fmt.Errorf("%v has effects but can't be equipped or used", kind.Name)
+	case kind.Equipped:
+		return fmt.Errorf("%v is equipped in the items file", kind.Name)
+	case kind.SpawnWeight < 0:
+		return fmt.Errorf("%v has a negative spawn weight", kind.Name)
+	case kind.MinDepth < 0 || (kind.MaxDepth != 0 && kind.MaxDepth < kind.MinDepth):
+		return fmt.Errorf("%v has an invalid depth range %d-%d", kind.Name, kind.MinDepth, kind.MaxDepth)
+	}
+
+	for _, infliction := range kind.Effects {
+		if err := infliction.validate(kind.Name, kind.Category == ITEM_CONSUMABLE); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validCategory(category ItemCategory) bool {
+	for _, c := range itemCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+func findItemKind(kinds []*ItemKind, name string) (*ItemKind, bool) {
+	for _, kind := range kinds {
+		if kind.Name == name {
+			return kind, true
+		}
+	}
+	return nil, false
+}
+
+func (kind *ItemKind) NewItem() *Item {
+	item := kind.Item
+	return &item
+}
+
+func (kind *ItemKind) weightAt(depth int) float64 {
+	if depth < kind.MinDepth || (kind.MaxDepth != 0 && depth > kind.MaxDepth) {
+		return 0
+	}
+	weight := kind.SpawnWeight + kind.WeightPerDepth*float64(depth-kind.MinDepth)
+	if weight < 0 {
+		return 0
+	}
+	return weight
+}
+
+// ItemTable picks the items lying around on a floor, like SpawnTable does enemies
+type ItemTable struct {
+	Kinds   []*ItemKind
+	Weights []float64
+	total   float64
+}
+
+func (data *Data) NewItemTable(depth int) *ItemTable {
+	var table ItemTable
+	for _, kind := range data.Items {
+		if weight := kind.weightAt(depth); weight > 0 {
+			table.Kinds = append(table.Kinds, kind)
+			table.Weights = append(table.Weights, weight)
+			table.total += weight
+		}
+	}
+	return &table
+}
+
+// Pick returns nil if nothing can appear on the floor
+func (table *ItemTable) Pick(rng *rand.Rand) *ItemKind {
+	if table.total <= 0 {
+		return nil
+	}
+	return table.Kinds[weightedPick(rng, table.Weights, table.total)]
+}
+
+// Index of a weight picked at random, the bigger the weight the likelier
+func weightedPick(rng *rand.Rand, weights []float64, total float64) int {
+	roll := rng.Float64() * total
+	for i, weight := range weights {
+		if roll < weight {
+			return i
+		}
+		roll -= weight
+	}
+	return len(weights) - 1
+}
+
+// Items are left on the spawn points no enemy took, the player's own included
+func placeItems(data *Data, tiles [][]*Tile, enemies []*Enemy, depth int, rng *rand.Rand) []*FloorItem {
+	t := time.Now()
+	taken := make(map[IVector2]bool)
+	for _, enemy := range enemies {
+		taken[enemy.Pos] = true
+	}
+
+	table := data.NewItemTable(depth)
+	var items []*FloorItem
+	for _, row := range tiles {
+		for _, tile := range row {
+			if tile != nil && tile.Type == TILE_FLOOR_SPAWN && !taken[tile.Pos] {
+				if rng.Float32() < ITEM_SPAWN_RATE {
+					if kind := table.Pick(rng); kind != nil {
+						items = append(items, &FloorItem{Pos: tile.Pos, Item: kind.NewItem()})
+					}
+				}
+			}
+		}
+	}
+	log.Println("Item placement complete in ", time.Since(t))
+	return items
+}
+
+// Inventory is what the player carries, limited by INVENTORY_SLOTS and how much they can lift
+type Inventory struct {
+	Items []*Item
+	// Index of the item the inventory panel points at, dropping and using act on it
+	Selected int
+}
+
+func (inventory *Inventory) Weight() float32 {
+	weight := float32(0.0)
+	for _, item := range inventory.Items {
+		weight += item.Weight
+	}
+	return weight
+}
+
+func (inventory *Inventory) Has(category ItemCategory) bool {
+	for _, item := range inventory.Items {
+		if item.Category == category {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectedItem is nil if the inventory is empty
+func (inventory *Inventory) SelectedItem() *Item {
+	if inventory.Selected < 0 || inventory.Selected >= len(inventory.Items) {
+		return nil
+	}
+	return inventory.Items[inventory.Selected]
+}
+
+// Select moves the selection by offset, wrapping around at either end
+func (inventory *Inventory) Select(offset int) {
+	count := len(inventory.Items)
+	if count == 0 {
+		inventory.Selected = 0
+		return
+	}
+	inventory.Selected = ((inventory.Selected+offset)%count + count) % count
+}
+
+func (inventory *Inventory) insert(index int, item *Item) {
+	inventory.Items = append(inventory.Items, nil)
+	copy(inventory.Items[index+1:], inventory.Items[index:])
+	inventory.Items[index] = item
+}
+
+func (inventory *Inventory) remove(index int) *Item {
+	item := inventory.Items[index]
+	inventory.Items = append(inventory.Items[:index], inventory.Items[index+1:]...)
+	if inventory.Selected >= len(inventory.Items) && inventory.Selected > 0 {
+		inventory.Selected--
+	}
+	return item
+}
+
+func (player *Player) CarryLimit() float32 {
+	return CARRY_WEIGHT + CARRY_WEIGHT_PER_STRENGTH*float32(player.EffectiveStats().Strength)
+}
+
+func (player *Player) canCarry(item *Item) error {
+	if len(player.Inventory.Items) >= INVENTORY_SLOTS {
+		return errors.New("no room in the inventory")
+	}
+	if player.Inventory.Weight()+item.Weight > player.CarryLimit() {
+		return errors.New("too heavy to carry")
+	}
+	return nil
+}
+
+// The topmost item at pos, the one dropped last
+func (world *World) itemAt(pos IVector2) (int, bool) {
+	for i := len(world.Items) - 1; i >= 0; i-- {
+		if world.Items[i].Pos == pos {
+			return i, true
+		}
+	}
+	return -1, false
+}
@@ -0,0 +1,247 @@
+package sim
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"testing"
+)
+
+func TestShippedItems(t *testing.T) {
+	path := "../" + ITEMS_FILE
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ParseItems(path, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	categories := make(map[ItemCategory]bool)
+	for _, kind := range loaded {
+		categories[kind.Category] = true
+	}
+	for _, category := range itemCategories {
+		if !categories[category] {
+			t.Errorf("no %v items", category)
+		}
+	}
+}
+
+func TestParseItemsErrors(t *testing.T) {
+	cases := map[string]string{
+		"no items":         `{"items": []}`,
+		"missing name":     `{"items": [{"category": "key"}]}`,
+		"unknown category": `{"items": [{"name": "a", "category": "hat"}]}`,
+		"negative weight":  `{"items": [{"name": "a", "category": "key", "weight": -1}]}`,
+		"healing key":      `{"items": [{"name": "a", "category": "key", "heal": 3}]}`,
+		"spawn weight":     `{"items": [{"name": "a", "category": "key", "spawnWeight": -1}]}`,
+		"depth range":      `{"items": [{"name": "a", "category": "key", "minDepth": 3, "maxDepth": 2}]}`,
+		"defined twice":    `{"items": [{"name": "a", "category": "key"}, {"name": "a", "category": "weapon"}]}`,
+		"unknown effect":   `{"items": [{"name": "a", "category": "weapon", "effects": [{"effect": "fire", "turns": 1, "chance": 1}]}]}`,
+		"no turns":         `{"items": [{"name": "a", "category": "weapon", "effects": [{"effect": "stun", "chance": 1}]}]}`,
+		"no chance":        `{"items": [{"name": "a", "category": "weapon", "effects": [{"effect": "stun", "turns": 1}]}]}`,
+		"drinking chance":  `{"items": [{"name": "a", "category": "consumable", "effects": [{"effect": "haste", "turns": 1, "chance": 0.5}]}]}`,
+		"cursed key":       `{"items": [{"name": "a", "category": "key", "effects": [{"effect": "blind", "turns": 1}]}]}`,
+	}
+	for name, data := range cases {
+		if _, err := ParseItems("test.json", []byte(data)); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
+
+func TestItemTableDepths(t *testing.T) {
+	bread := &ItemKind{Item: Item{Name: "bread", Category: ITEM_CONSUMABLE}, SpawnWeight: 2}
+	pick := &ItemKind{Item: Item{Name: "pick", Category: ITEM_DIGGING_TOOL}, SpawnWeight: 1, MinDepth: 2}
+	data := &Data{Items: []*ItemKind{bread, pick}}
+
+	if table := data.NewItemTable(0); len(table.Kinds) != 1 || table.Kinds[0] != bread {
+		t.Errorf("expected only bread on the first floor, got %v", table.Kinds)
+	}
+	table := data.NewItemTable(2)
+	if len(table.Kinds) != 2 {
+		t.Fatalf("expected both items on the third floor, got %v", table.Kinds)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	picks := 0
+	for i := 0; i < 3000; i++ {
+		if table.Pick(rng) == pick {
+			picks++
+		}
+	}
+	if picks < 850 || picks > 1150 {
+		t.Errorf("the pick was picked %d times out of 3000", picks)
+	}
+
+	item := bread.NewItem()
+	item.Name = "stale bread"
+	if bread.Name != "bread" {
+		t.Error("the kind itself was changed")
+	}
+}
+
+func TestInventoryLimits(t *testing.T) {
+	player := NewPlayer()
+	stone := &Item{Name: "stone", Category: ITEM_KEY, Weight: 1}
+	for i := 0; i < INVENTORY_SLOTS; i++ {
+		if err := player.canCarry(stone); err != nil {
+			t.Fatalf("couldn't carry stone %d: %v", i+1, err)
+		}
+		player.Inventory.Items = append(player.Inventory.Items, stone)
+	}
+	if player.canCarry(stone) == nil {
+		t.Error("carried more items than there are slots")
+	}
+
+	player.Inventory.Items = nil
+	boulder := &Item{Name: "boulder", Category: ITEM_KEY, Weight: player.CarryLimit() + 1}
+	if player.canCarry(boulder) == nil {
+		t.Error("carried more weight than the limit")
+	}
+	player.Stats.Strength += 1
+	if player.canCarry(boulder) != nil {
+		t.Error("strength didn't raise the weight limit")
+	}
+}
+
+func TestInventorySelection(t *testing.T) {
+	var inventory Inventory
+	inventory.Select(1)
+	if inventory.SelectedItem() != nil {
+		t.Error("selected an item in an empty inventory")
+	}
+
+	a, b, c := &Item{Name: "a"}, &Item{Name: "b"}, &Item{Name: "c"}
+	inventory.Items = []*Item{a, b, c}
+	inventory.Select(-1)
+	if inventory.SelectedItem() != c {
+		t.Errorf("expected the selection to wrap to the last item, got %v", inventory.SelectedItem())
+	}
+	inventory.remove(2)
+	if inventory.SelectedItem() != b {
+		t.Errorf("expected the selection to move to the new last item, got %v", inventory.SelectedItem())
+	}
+	inventory.insert(0, c)
+	if inventory.Items[0] != c || inventory.Items[1] != a {
+		t.Errorf("inserted in the wrong place: %v", inventory.Items)
+	}
+}
+
+func TestPickUpAndDrop(t *testing.T) {
+	world := newCommandTestWorld(t)
+	bread := &Item{Name: "bread", Category: ITEM_CONSUMABLE, Weight: 0.5, Heal: 5}
+	pick := &Item{Name: "pick", Category: ITEM_DIGGING_TOOL, Weight: 4}
+	world.Items = []*FloorItem{{Pos: world.Player.Pos, Item: bread}, {Pos: world.Player.Pos, Item: pick}}
+	actions := world.Player.Turn.Actions
+
+	if err := world.Execute(NewPickUpCommand(world)); err != nil {
+		t.Fatal(err)
+	}
+	if len(world.Player.Inventory.Items) != 1 || world.Player.Inventory.Items[0] != pick || len(world.Items) != 1 {
+		t.Fatalf("expected the item on top to be picked up, carrying %v", world.Player.Inventory.Items)
+	}
+	if world.Player.Turn.Actions != actions-1 {
+		t.Errorf("picking up took %d actions", actions-world.Player.Turn.Actions)
+	}
+
+	world.Player.Pos = tilePos(2, 1)
+	if err := world.Execute(NewDropCommand(world)); err != nil {
+		t.Fatal(err)
+	}
+	if len(world.Player.Inventory.Items) != 0 || len(world.Items) != 2 || world.Items[1].Pos != tilePos(2, 1) {
+		t.Errorf("the item wasn't dropped where the player stands: %v", world.Items)
+	}
+	if world.Execute(NewPickUpCommand(world)) != nil || world.Execute(NewDropCommand(world)) == nil {
+		t.Error("kept going with no actions left")
+	}
+
+	for world.Commands.Undo(world) {
+	}
+	if len(world.Player.Inventory.Items) != 0 || len(world.Items) != 2 || world.Items[1].Item != pick || world.Items[1].Pos != tilePos(1, 1) {
+		t.Errorf("undo didn't put the items back: %v", world.Items)
+	}
+	if world.Player.Turn.Actions != actions {
+		t.Errorf("undo left %d actions, expected %d", world.Player.Turn.Actions, actions)
+	}
+}
+
+func TestUseItem(t *testing.T) {
+	world := newCommandTestWorld(t)
+	bread := &Item{Name: "bread", Category: ITEM_CONSUMABLE, Heal: 5}
+	key := &Item{Name: "key", Category: ITEM_KEY}
+	world.Player.Inventory.Items = []*Item{key, bread}
+	world.Player.Health = world.Player.MaxHealth() - 2
+
+	if world.Execute(NewUseItemCommand(world)) == nil {
+		t.Error("used a key")
+	}
+	world.Player.Inventory.Select(1)
+	if err := world.Execute(NewUseItemCommand(world)); err != nil {
+		t.Fatal(err)
+	}
+	if world.Player.Health != world.Player.MaxHealth() {
+		t.Errorf("expected healing up to max health, got %v", world.Player.Health)
+	}
+	if len(world.Player.Inventory.Items) != 1 {
+		t.Error("the bread wasn't used up")
+	}
+
+	world.Commands.Undo(world)
+	if world.Player.Health != world.Player.MaxHealth()-2 || world.Player.Inventory.Items[1] != bread {
+		t.Error("undo didn't give the bread back")
+	}
+}
+
+func TestDiggingTool(t *testing.T) {
+	world := newCommandTestWorld(t)
+	world.Player.Inventory.Items = []*Item{{Name: "pick", Category: ITEM_DIGGING_TOOL}}
+	turn := world.Player.Turn
+
+	if err := world.Execute(NewDigCommand(tilePos(1, 0))); err != nil {
+		t.Fatal(err)
+	}
+	if world.Player.Turn.Actions != turn.Actions || world.Player.Turn.Movement != turn.Movement-1 {
+		t.Errorf("digging with a tool should take movement, turn went from %+v to %+v", turn, world.Player.Turn)
+	}
+	world.Commands.Undo(world)
+	if world.Player.Turn != turn {
+		t.Errorf("undo left the turn at %+v", world.Player.Turn)
+	}
+
+	// Without movement left it falls back to an action
+	world.Player.Turn.Movement = 0
+	if err := world.Execute(NewDigCommand(tilePos(1, 0))); err != nil {
+		t.Fatal(err)
+	}
+	if world.Player.Turn.Actions != turn.Actions-1 {
+		t.Error("digging without movement didn't take an action")
+	}
+}
+
+func TestPlaceItems(t *testing.T) {
+	data := &Data{Items: []*ItemKind{{Item: Item{Name: "bread", Category: ITEM_CONSUMABLE}, SpawnWeight: 1}}}
+
+	world := newTestWorld(t, 1, `
+@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
+@PPPPPPPPPPPPPPPPPPPPPPPPPPPPPPPPPPPPPP@
+@PPPPPPPPPPPPPPPPPPPPPPPPPPPPPPPPPPPPPP@
+@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
+`)
+	enemy := goblinArchetype.NewEnemy(tilePos(1, 1), 0)
+	items := placeItems(data, world.Map, []*Enemy{enemy}, 0, rand.New(rand.NewSource(3)))
+	if len(items) == 0 {
+		t.Fatal("no items were placed")
+	}
+	for _, item := range items {
+		if item.Pos == enemy.Pos {
+			t.Error("an item was placed under an enemy")
+		}
+	}
+
+	again := placeItems(data, world.Map, []*Enemy{enemy}, 0, rand.New(rand.NewSource(3)))
+	if len(again) != len(items) {
+		t.Error("the same seed placed different items")
+	}
+}
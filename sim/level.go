@@ -0,0 +1,258 @@
+package sim
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const LEVELS_FOLDER = "assets/levels/"
+
+const MAP_GLYPHS = "@_!P-<>"
+const LEVEL_GRID_SEPARATOR = "---"
+
+// LevelFile is a level loaded from a .map file.
+// The header lines are "key: value" pairs, the glyph grid follows the separator line
+// and needs a player spawn and down stairs
+//
+//	name: Tutorial
+//	size: 12x8
+//	tileset: wall_stone_tile
+//	enemy: goblin 4,3
+//	---
+//	@@@@@@@@@@@@
+//	...
+//
+// Instead of a glyph grid a level can name a generator ("generator: bsp"),
+// in which case the map is generated from the seed. Generated levels
+// can also tune the generator with the keys in GeneratorConfigKeys.
+type LevelFile struct {
+	Path      string
+	Name      string
+	TileSet   string
+	Generator string
+	Config    GeneratorConfig
+	Enemies   []LevelEnemy
+	MapString string
+}
+
+type LevelEnemy struct {
+	Kind string
+	Pos  IVector2
+
+	line int
+}
+
+// ResolveLevelPath accepts a path to a file or the name of a level in assets/levels
+func ResolveLevelPath(path string) string {
+	candidates := []string{
+		path,
+		LEVELS_FOLDER + path,
+		LEVELS_FOLDER + path + ".map",
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return path
+}
+
+func LoadLevelFile(path string, data *Data) (*LevelFile, error) {
+	path = ResolveLevelPath(path)
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseLevel(path, string(text), data)
+}
+
+// ParseLevel checks that the enemies of the level are among the archetypes of data
+func ParseLevel(path string, text string, data *Data) (*LevelFile, error) {
+	level := LevelFile{
+		Path:   path,
+		Name:   strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Config: DefaultGeneratorConfig(),
+	}
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	seen := make(map[string]bool)
+	gridStart := -1
+
+	for i, line := range lines {
+		lineNum := i + 1
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if trimmed == LEVEL_GRID_SEPARATOR {
+			gridStart = i + 1
+			break
+		}
+
+		split := strings.SplitN(trimmed, ":", 2)
+		if len(split) != 2 {
+			return nil, levelError(path, lineNum, 0, "expected \"key: value\" header, got %q", trimmed)
+		}
+		key := strings.ToLower(strings.TrimSpace(split[0]))
+		value := strings.TrimSpace(split[1])
+
+		if seen[key] && key != "enemy" {
+			return nil, levelError(path, lineNum, 0, "duplicate header %q", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "name":
+			level.Name = value
+		case "size", "noise-scale", "obstacle-threshold", "wall-threshold", "floor-threshold", "spawn-rate":
+			if err := level.Config.Set(key, value); err != nil {
+				return nil, levelError(path, lineNum, 0, "%v", err)
+			}
+		case "tileset":
+			// Checked by the front-end, which is the one drawing it
+			level.TileSet = value
+		case "generator":
+			if _, ok := GetGenerator(value); !ok {
+				return nil, levelError(path, lineNum, 0, "unknown generator %q, expected one of %v", value, GeneratorNames())
+			}
+			level.Generator = strings.ToLower(value)
+		case "enemy":
+			enemy, err := parseLevelEnemy(value, data)
+			if err != nil {
+				return nil, levelError(path, lineNum, 0, "%v", err)
+			}
+			enemy.line = lineNum
+			level.Enemies = append(level.Enemies, enemy)
+		default:
+			return nil, levelError(path, lineNum, 0, "unknown header %q", key)
+		}
+	}
+
+	if seen["generator"] {
+		if gridStart >= 0 {
+			return nil, levelError(path, gridStart, 0, "generated levels can't have a map grid")
+		}
+		if len(level.Enemies) > 0 {
+			return nil, levelError(path, level.Enemies[0].line, 0, "enemies can only be placed on levels with a map grid")
+		}
+		if err := level.Config.Validate(); err != nil {
+			return nil, levelError(path, 0, 0, "%v", err)
+		}
+		return &level, nil
+	}
+
+	for _, key := range GeneratorConfigKeys {
+		if seen[key] && key != "size" {
+			return nil, levelError(path, 0, 0, "%v only applies to generated levels", key)
+		}
+	}
+
+	if gridStart < 0 {
+		return nil, levelError(path, len(lines), 0, "missing %q line before the map grid", LEVEL_GRID_SEPARATOR)
+	}
+	if !seen["size"] {
+		return nil, levelError(path, 1, 0, "missing size header")
+	}
+
+	var rows []string
+	players := 0
+	for i := gridStart; i < len(lines); i++ {
+		row := strings.TrimRight(lines[i], " \t")
+		if row == "" {
+			// Allow trailing empty lines only
+			for j := i; j < len(lines); j++ {
+				if strings.TrimSpace(lines[j]) != "" {
+					return nil, levelError(path, i+1, 0, "empty line inside the map grid")
+				}
+			}
+			break
+		}
+
+		lineNum := i + 1
+		for col, char := range row {
+			if !strings.ContainsRune(MAP_GLYPHS, char) {
+				return nil, levelError(path, lineNum, col+1, "unknown glyph %q", char)
+			}
+			if char == 'P' {
+				players++
+				if players > 1 {
+					return nil, levelError(path, lineNum, col+1, "more than one player spawn")
+				}
+			}
+		}
+		if len(row) != level.Config.Width {
+			return nil, levelError(path, lineNum, 0, "row is %d tiles wide, size header says %d", len(row), level.Config.Width)
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) != level.Config.Height {
+		return nil, levelError(path, gridStart+len(rows), 0, "map grid is %d rows tall, size header says %d", len(rows), level.Config.Height)
+	}
+
+	if !strings.ContainsAny(strings.Join(rows, ""), "P<") {
+		return nil, levelError(path, gridStart+1, 0, "map grid has no player spawn (P or <)")
+	}
+	if !strings.ContainsRune(strings.Join(rows, ""), '>') {
+		return nil, levelError(path, gridStart+1, 0, "map grid has no down stairs (>)")
+	}
+
+	for _, enemy := range level.Enemies {
+		x := int(enemy.Pos.X)
+		y := int(enemy.Pos.Y)
+		if x >= level.Config.Width || y >= level.Config.Height {
+			return nil, levelError(path, enemy.line, 0, "enemy %v at %d,%d is outside the map", enemy.Kind, x, y)
+		}
+		switch rows[y][x] {
+		case '@', '!':
+			return nil, levelError(path, gridStart+y+1, x+1, "enemy %v is placed inside a wall", enemy.Kind)
+		case 'P', '<':
+			return nil, levelError(path, gridStart+y+1, x+1, "enemy %v is placed on the player spawn", enemy.Kind)
+		}
+	}
+
+	level.MapString = strings.Join(rows, "\n")
+	return &level, nil
+}
+
+func parseLevelEnemy(value string, data *Data) (LevelEnemy, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return LevelEnemy{}, fmt.Errorf("enemy should be \"kind x,y\", got %q", value)
+	}
+
+	if _, ok := data.GetArchetype(fields[0]); !ok {
+		return LevelEnemy{}, fmt.Errorf("unknown enemy kind %q", fields[0])
+	}
+
+	coords := strings.Split(fields[1], ",")
+	if len(coords) != 2 {
+		return LevelEnemy{}, fmt.Errorf("enemy position should be x,y, got %q", fields[1])
+	}
+	x, errX := strconv.Atoi(coords[0])
+	y, errY := strconv.Atoi(coords[1])
+	if errX != nil || errY != nil || x < 0 || y < 0 {
+		return LevelEnemy{}, fmt.Errorf("enemy position should be x,y, got %q", fields[1])
+	}
+
+	return LevelEnemy{
+		Kind: fields[0],
+		Pos:  NewIVector2(int32(x), int32(y)),
+	}, nil
+}
+
+func levelError(path string, line int, col int, format string, args ...interface{}) error {
+	location := path
+	if line > 0 {
+		location = fmt.Sprintf("%v:%d", location, line)
+	}
+	if col > 0 {
+		location = fmt.Sprintf("%v:%d", location, col)
+	}
+	return fmt.Errorf("%v: %v", location, fmt.Sprintf(format, args...))
+}
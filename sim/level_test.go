@@ -0,0 +1,72 @@
+package sim
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const levelTestGrid = `size: 5x3
+---
+@@@@@
+@P_>@
+@@@@@
+`
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("levels/test.map", "name: Test\nenemy: goblin 2,1\n"+levelTestGrid, DefaultData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level.Name != "Test" || level.MapString != "@@@@@\n@P_>@\n@@@@@" || len(level.Enemies) != 1 || level.Enemies[0].Pos != NewIVector2(2, 1) {
+		t.Errorf("level parsed wrong: %+v", *level)
+	}
+}
+
+func TestShippedLevels(t *testing.T) {
+	data := loadShippedData(t)
+	paths, err := filepath.Glob("../" + LEVELS_FOLDER + "*.map")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no levels in %v: %v", LEVELS_FOLDER, err)
+	}
+	for _, path := range paths {
+		if _, err := LoadLevelFile(path, data); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestParseLevelErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     string
+		location string
+		message  string
+	}{
+		{"unknown glyph", "size: 4x3\n---\n@@@@\n@P?@\n@@@@\n", "test.map:4:3", "unknown glyph"},
+		{"ragged row", "size: 4x3\n---\n@@@@\n@P_@@\n@@@@\n", "test.map:4", "5 tiles wide"},
+		{"short grid", "size: 4x3\n---\n@@@@\n@P_@\n", "test.map:4", "2 rows tall"},
+		{"missing player", "size: 4x3\n---\n@@@@\n@__@\n@@@@\n", "test.map:3", "no player spawn"},
+		{"multiple players", "size: 4x3\n---\n@@@@\n@PP@\n@@@@\n", "test.map:4:3", "more than one player"},
+		{"missing exit", "size: 4x3\n---\n@@@@\n@P_@\n@@@@\n", "test.map:3", "no down stairs"},
+		{"unknown header", "name: Test\ncolour: red\n" + levelTestGrid, "test.map:2", "unknown header"},
+		{"duplicate header", "name: a\nname: b\n" + levelTestGrid, "test.map:2", "duplicate header"},
+		{"not a header", "# comment\nsize 4x3\n---\n", "test.map:2", "expected \"key: value\""},
+		{"bad size", "size: big\n---\n", "test.map:1", "size"},
+		{"unknown enemy", "enemy: dragon 1,1\n" + levelTestGrid, "test.map:1", "unknown enemy kind"},
+		{"enemy in a wall", "enemy: goblin 0,0\n" + levelTestGrid, "test.map:4:1", "inside a wall"},
+		{"enemy on the player", "enemy: goblin 1,1\n" + levelTestGrid, "test.map:5:2", "on the player spawn"},
+		{"enemy on the entry", "enemy: goblin 1,1\nsize: 4x3\n---\n@@@@\n@<>@\n@@@@\n", "test.map:5:2", "on the player spawn"},
+		{"missing separator", "size: 4x3\n", "test.map:2", "missing \"---\""},
+	}
+	for _, c := range cases {
+		_, err := ParseLevel("test.map", c.data, DefaultData())
+		if err == nil {
+			t.Errorf("%v: expected an error", c.name)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), c.location+": ") || !strings.Contains(err.Error(), c.message) {
+			t.Errorf("%v: expected %q at %v, got %q", c.name, c.message, c.location, err)
+		}
+	}
+}
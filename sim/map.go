@@ -0,0 +1,189 @@
+package sim
+
+import (
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+const ENEMY_SPAWN_RATE = 0.7
+const ENEMY_SPAWN_RATE_PER_DEPTH = 0.05
+
+// GenerateLevel places the enemies and items of data on the floor
+func GenerateLevel(seed int64, depth int, level *LevelFile, data *Data) *Floor {
+	t := time.Now()
+	log.Printf("Generating floor %d with seed %d", depth, seed)
+	rng := rand.New(rand.NewSource(seed))
+
+	floor := Floor{
+		Depth: depth,
+		Seed:  seed,
+	}
+	if level.MapString != "" {
+		log.Printf("Loading level %v from %v", level.Name, level.Path)
+		floor.Map, floor.Entry = generateTiles(level.MapString, rng)
+		floor.Enemies = placeLevelEnemies(data, level.Enemies, depth)
+	} else {
+		generator, ok := GetGenerator(level.Generator)
+		if !ok {
+			log.Printf("Unknown generator %q, using %v", level.Generator, DEFAULT_GENERATOR)
+			generator, _ = GetGenerator(DEFAULT_GENERATOR)
+		}
+		mapstring := ConnectMap(generator.Generate(level.Config, rng), rng)
+		mapstring = PlaceStairs(mapstring, rng)
+		floor.Map, floor.Entry = generateTiles(mapstring, rng)
+		floor.Enemies = placeEnemies(data, floor.Map, floor.Entry, depth, rng)
+		floor.Items = placeItems(data, floor.Map, floor.Enemies, depth, rng)
+	}
+	floor.Exit, floor.HasExit = findTile(floor.Map, TILE_STAIRS_DOWN)
+
+	log.Println("Level generated in ", time.Since(t))
+	return &floor
+}
+
+func placeEnemies(data *Data, tiles [][]*Tile, spawn IVector2, depth int, rng *rand.Rand) []*Enemy {
+	t := time.Now()
+	spawnRate := float32(ENEMY_SPAWN_RATE + ENEMY_SPAWN_RATE_PER_DEPTH*float64(depth))
+	table := data.NewSpawnTable(depth)
+	var enemies []*Enemy
+	for _, row := range tiles {
+		for _, tile := range row {
+			if tile != nil && tile.Type == TILE_FLOOR_SPAWN && tile.Pos != spawn {
+				if rng.Float32() < spawnRate {
+					if archetype := table.Pick(rng); archetype != nil {
+						enemies = append(enemies, archetype.NewEnemy(tile.Pos, depth))
+					}
+				}
+			}
+		}
+	}
+	log.Println("Enemy placement complete in ", time.Since(t))
+	return enemies
+}
+
+func placeLevelEnemies(data *Data, levelEnemies []LevelEnemy, depth int) []*Enemy {
+	var enemies []*Enemy
+	for _, levelEnemy := range levelEnemies {
+		pos := NewIVector2(levelEnemy.Pos.X*TILE_SIZE, levelEnemy.Pos.Y*TILE_SIZE)
+		archetype, _ := data.GetArchetype(levelEnemy.Kind)
+		enemies = append(enemies, archetype.NewEnemy(pos, depth))
+	}
+	return enemies
+}
+
+// PlaceStairs puts the up stairs on a random spawn point and the down stairs
+// on one of the floor tiles furthest away from it
+func PlaceStairs(mapstring string, rng *rand.Rand) string {
+	grid := parseGlyphGrid(mapstring)
+
+	var spawns []gridCell
+	for y, row := range grid {
+		for x, glyph := range row {
+			if glyph == 'P' {
+				spawns = append(spawns, gridCell{X: x, Y: y})
+			}
+		}
+	}
+	if len(spawns) == 0 {
+		return mapstring
+	}
+
+	entry := spawns[rng.Intn(len(spawns))]
+	grid[entry.Y][entry.X] = '<'
+
+	distances := gridDistances(grid, entry)
+	furthest := 0
+	for y, row := range distances {
+		for x, distance := range row {
+			if grid[y][x] == '_' && distance > furthest {
+				furthest = distance
+			}
+		}
+	}
+
+	// Pick from the tiles in the last 10% of the distance for some variety
+	var candidates []gridCell
+	for y, row := range distances {
+		for x, distance := range row {
+			if grid[y][x] == '_' && distance > 0 && distance >= furthest*9/10 {
+				candidates = append(candidates, gridCell{X: x, Y: y})
+			}
+		}
+	}
+	if len(candidates) > 0 {
+		exit := candidates[rng.Intn(len(candidates))]
+		grid[exit.Y][exit.X] = '>'
+	}
+
+	return grid.String()
+}
+
+// Returns the tile at which the player enters the floor
+func generateTiles(mapstring string, rng *rand.Rand) ([][]*Tile, IVector2) {
+	t := time.Now()
+	rows := strings.Split(strings.TrimRight(mapstring, "\n"), "\n")
+	width := 0
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+
+	tiles := make([][]*Tile, width)
+	for i := 0; i < width; i++ {
+		tiles[i] = make([]*Tile, len(rows))
+	}
+
+	spawn := NewIVector2(PLAYER_OFFSET_X, PLAYER_OFFSET_Y)
+	spawnFound := false
+	stairsFound := false
+	for y, row := range rows {
+		for x, char := range strings.Split(row, "") {
+			pos_x := int32(x) * TILE_SIZE
+			pos_y := int32(y) * TILE_SIZE
+			if char == "<" && !stairsFound {
+				spawn = NewIVector2(pos_x+PLAYER_OFFSET_X, pos_y+PLAYER_OFFSET_Y)
+				stairsFound = true
+			}
+			if char == "P" && !stairsFound {
+				if !spawnFound || rng.Float32() < 0.1 {
+					spawn = NewIVector2(pos_x+PLAYER_OFFSET_X, pos_y+PLAYER_OFFSET_Y)
+					spawnFound = true
+				}
+			}
+			pos := IVector2{X: pos_x, Y: pos_y}
+			tile := charToTile(char, pos, rng)
+			tiles[x][y] = &tile
+		}
+	}
+	log.Println("Tiles generated in ", time.Since(t))
+	debugPrint(mapstring)
+	return tiles, spawn
+}
+
+func findTile(tiles [][]*Tile, tileType int) (IVector2, bool) {
+	for _, row := range tiles {
+		for _, tile := range row {
+			if tile != nil && tile.Type == tileType {
+				return tile.Pos, true
+			}
+		}
+	}
+	return IVector2{}, false
+}
+
+func (world *World) GetMapTile(pos IVector2) (*Tile, bool) {
+	x := pos.X / TILE_SIZE
+	y := pos.Y / TILE_SIZE
+
+	if pos.X < 0 || pos.Y < 0 || int(x) >= len(world.Map) || int(y) >= len(world.Map[x]) {
+		return nil, false
+	}
+
+	if tile := world.Map[x][y]; tile != nil {
+		return tile, true
+	} else {
+		return nil, false
+	}
+}
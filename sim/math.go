@@ -0,0 +1,57 @@
+package sim
+
+import (
+	"math"
+)
+
+// Positions in the simulation are in pixels, one tile is TILE_SIZE wide
+type IVector2 struct {
+	X int32
+	Y int32
+}
+
+func NewIVector2(x int32, y int32) IVector2 {
+	return IVector2{
+		X: x,
+		Y: y,
+	}
+}
+
+// Distance between two positions in tiles
+func distance(a IVector2, b IVector2) float32 {
+	dx := float64(a.X - b.X)
+	dy := float64(a.Y - b.Y)
+	return float32(math.Hypot(dx, dy)) / float32(TILE_SIZE)
+}
+
+func calculateLightLevel(distance float32, visibilityStat uint8) uint8 {
+	// Nothing lights anything without any visibility
+	if visibilityStat == 0 {
+		return 0
+	}
+	distance_alpha := distance / float32(visibilityStat)
+	if distance_alpha < 0.0 {
+		distance_alpha = 0.0
+	} else if distance_alpha > 1.0 {
+		distance_alpha = 1.0
+	}
+	alpha := uint8(255.0 * distance_alpha)
+	// Reverse alpha to make closer objects brighter instead of darker
+	return uint8(math.Abs(float64(alpha) - 255.0))
+}
+
+func sign(v int) int {
+	if v > 0 {
+		return 1
+	} else if v < 0 {
+		return -1
+	}
+	return 0
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
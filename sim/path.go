@@ -0,0 +1,57 @@
+package sim
+
+import (
+	"pathfinding"
+)
+
+// Enemies move like the player, one axis at a time
+const ENEMY_DIAGONALS = pathfinding.DIAGONAL_NEVER
+
+// Path cost of stepping on each tile type, tiles not listed cost 1
+var TILE_PATH_COSTS = map[int]float64{
+	TILE_FLOOR_OBS: 3.0,
+}
+
+func pathPoint(pos IVector2) pathfinding.Point {
+	return pathfinding.Point{X: int(pos.X / TILE_SIZE), Y: int(pos.Y / TILE_SIZE)}
+}
+
+func pathPos(point pathfinding.Point) IVector2 {
+	return NewIVector2(int32(point.X)*TILE_SIZE, int32(point.Y)*TILE_SIZE)
+}
+
+// Path cost for the given character, other characters are obstacles
+func pathCost(world *World, self *Enemy) func(x int, y int) float64 {
+	return func(x int, y int) float64 {
+		pos := pathPos(pathfinding.Point{X: x, Y: y})
+		tile, ok := world.GetMapTile(pos)
+		if !ok || tile.Block || pos == world.Player.Pos {
+			return pathfinding.BLOCKED
+		}
+		for _, enemy := range world.Enemies {
+			if enemy != self && enemy.Pos == pos {
+				return pathfinding.BLOCKED
+			}
+		}
+		if cost, ok := TILE_PATH_COSTS[tile.Type]; ok {
+			return cost
+		}
+		return 1.0
+	}
+}
+
+// Returns the pixel positions of the tiles between the enemy and target, ending at target.
+// If target can't be reached the path leads as close to it as possible.
+func (enemy *Enemy) PathTo(world *World, target IVector2) ([]IVector2, bool) {
+	points, ok := pathfinding.FindPath(pathPoint(enemy.Pos), pathPoint(target), pathfinding.Options{
+		Diagonals: ENEMY_DIAGONALS,
+		Cost:      pathCost(world, enemy),
+		Closest:   true,
+	})
+
+	path := make([]IVector2, len(points))
+	for i, point := range points {
+		path[i] = pathPos(point)
+	}
+	return path, ok
+}
@@ -0,0 +1,58 @@
+package sim
+
+import "math/rand"
+
+// RandomSource is a seeded source that counts the numbers it has handed out,
+// so its state can be saved as the seed and the count and restored by skipping ahead
+type RandomSource struct {
+	seed   int64
+	calls  uint64
+	source rand.Source64
+}
+
+func NewRandomSource(seed int64, calls uint64) *RandomSource {
+	source := RandomSource{
+		seed:   seed,
+		source: rand.NewSource(seed).(rand.Source64),
+	}
+	for source.calls < calls {
+		source.Int63()
+	}
+	return &source
+}
+
+func (source *RandomSource) Int63() int64 {
+	source.calls++
+	return source.source.Int63()
+}
+
+func (source *RandomSource) Uint64() uint64 {
+	source.calls++
+	return source.source.Uint64()
+}
+
+func (source *RandomSource) Seed(seed int64) {
+	source.seed = seed
+	source.calls = 0
+	source.source.Seed(seed)
+}
+
+// State returns the seed and how many numbers have been drawn since
+func (source *RandomSource) State() (int64, uint64) {
+	return source.seed, source.calls
+}
+
+// Spreads the seeds of the rounds out from the game's seed, the golden ratio in 64 bits
+const ROUND_SEED_MIX = -7046029254386353131
+
+// restoreRng puts the world's random numbers back to a state taken with State
+func (world *World) restoreRng(seed int64, calls uint64) {
+	world.rngSource = NewRandomSource(seed, calls)
+	world.rng = rand.New(world.rngSource)
+}
+
+// reseedRng starts the random numbers of a new round from the game's seed,
+// so that restoring them only skips the numbers drawn during the current round
+func (world *World) reseedRng() {
+	world.restoreRng(world.Seed^(int64(world.Turns.Round)*ROUND_SEED_MIX), 0)
+}
@@ -0,0 +1,274 @@
+package sim
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const REPLAY_VERSION = 1
+const REPLAY_INPUT_SEPARATOR = "---"
+
+// Replay is everything needed to play a run again: the settings the game was started with
+// and every recorded input, one line per frame in which something was pressed
+//
+//	version: 1
+//	seed: 1234
+//	generator: cave
+//	---
+//	right
+//	right down
+//	end-turn
+type Replay struct {
+	Path         string
+	Seed         int64
+	LevelPath    string
+	Generator    string
+	MapOverrides map[string]string
+	Inputs       []Input
+}
+
+func LoadReplay(path string) (*Replay, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseReplay(path, string(data))
+}
+
+func ParseReplay(path string, data string) (*Replay, error) {
+	replay := Replay{
+		Path:         path,
+		MapOverrides: make(map[string]string),
+	}
+
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	inputStart := -1
+	for i, line := range lines {
+		lineNum := i + 1
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if trimmed == REPLAY_INPUT_SEPARATOR {
+			inputStart = i + 1
+			break
+		}
+
+		split := strings.SplitN(trimmed, ":", 2)
+		if len(split) != 2 {
+			return nil, levelError(path, lineNum, 0, "expected \"key: value\" header, got %q", trimmed)
+		}
+		key := strings.ToLower(strings.TrimSpace(split[0]))
+		value := strings.TrimSpace(split[1])
+
+		switch key {
+		case "version":
+			version, err := strconv.Atoi(value)
+			if err != nil || version != REPLAY_VERSION {
+				return nil, levelError(path, lineNum, 0, "unsupported replay version %q", value)
+			}
+		case "seed":
+			seed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, levelError(path, lineNum, 0, "invalid seed %q", value)
+			}
+			replay.Seed = seed
+		case "level":
+			replay.LevelPath = value
+		case "generator":
+			if _, ok := GetGenerator(value); !ok {
+				return nil, levelError(path, lineNum, 0, "unknown generator %q, expected one of %v", value, GeneratorNames())
+			}
+			replay.Generator = value
+		default:
+			config := DefaultGeneratorConfig()
+			if err := config.Set(key, value); err != nil {
+				return nil, levelError(path, lineNum, 0, "%v", err)
+			}
+			replay.MapOverrides[key] = value
+		}
+	}
+
+	if inputStart < 0 {
+		return nil, levelError(path, len(lines), 0, "missing %q line before the inputs", REPLAY_INPUT_SEPARATOR)
+	}
+	if replay.Seed == 0 {
+		return nil, levelError(path, 1, 0, "missing seed header")
+	}
+
+	for i := inputStart; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" {
+			continue
+		}
+		input, ok := ParseInput(trimmed)
+		if !ok {
+			return nil, levelError(path, i+1, 0, "unknown input %q", trimmed)
+		}
+		replay.Inputs = append(replay.Inputs, input)
+	}
+
+	return &replay, nil
+}
+
+// Config is the game the replay was recorded in
+func (replay *Replay) Config() Config {
+	return Config{
+		Seed:         replay.Seed,
+		LevelPath:    replay.LevelPath,
+		Generator:    replay.Generator,
+		MapOverrides: replay.MapOverrides,
+	}
+}
+
+// ReplayInput plays recorded inputs back one per frame
+type ReplayInput struct {
+	Inputs []Input
+	next   int
+}
+
+// Poll leaves out what wouldn't be recorded now, like the debug inputs older replays may have
+func (replay *ReplayInput) Poll() Input {
+	if replay.Done() {
+		return 0
+	}
+	input := replay.Inputs[replay.next]
+	replay.next++
+	return input.Recorded()
+}
+
+func (replay *ReplayInput) Done() bool {
+	return replay.next >= len(replay.Inputs)
+}
+
+// ReplayRecorder passes the inputs of another source through, writing them to a replay file as they happen
+type ReplayRecorder struct {
+	source InputSource
+	file   *os.File
+}
+
+// The config must have the seed the game was started with, not 0
+func NewReplayRecorder(path string, source InputSource, config Config) (*ReplayRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header := fmt.Sprintf("version: %d\nseed: %d\n", REPLAY_VERSION, config.Seed)
+	if config.LevelPath != "" {
+		header += fmt.Sprintf("level: %v\n", config.LevelPath)
+	}
+	if config.Generator != "" {
+		header += fmt.Sprintf("generator: %v\n", config.Generator)
+	}
+	var keys []string
+	for key := range config.MapOverrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		header += fmt.Sprintf("%v: %v\n", key, config.MapOverrides[key])
+	}
+
+	if _, err := file.WriteString(header + REPLAY_INPUT_SEPARATOR + "\n"); err != nil {
+		file.Close()
+		return nil, err
+	}
+	log.Printf("Recording replay to %v", path)
+	return &ReplayRecorder{source: source, file: file}, nil
+}
+
+func (recorder *ReplayRecorder) Poll() Input {
+	input := recorder.source.Poll()
+	if input.Has(INPUT_QUICKLOAD) {
+		log.Println("Quickloading while recording, the replay won't match from here on")
+	}
+	if recorded := input.Recorded(); recorded != 0 {
+		if _, err := fmt.Fprintln(recorder.file, recorded); err != nil {
+			log.Println("Couldn't write replay: ", err)
+		}
+	}
+	return input
+}
+
+// StateHash sums up the simulation so runs can be compared, it leaves out anything only used for drawing
+func (world *World) StateHash() uint64 {
+	hash := fnv.New64a()
+	write := func(values ...interface{}) {
+		for _, value := range values {
+			binary.Write(hash, binary.LittleEndian, value)
+		}
+	}
+	writeTurn := func(turn *TurnData) {
+		write(turn.Movement, turn.Actions, turn.Done, int64(turn.Energy))
+	}
+	writeEffects := func(effects Effects) {
+		for _, effect := range effects {
+			write([]byte(effect.Kind), int64(effect.Turns), int64(effect.Stacks))
+		}
+	}
+
+	write(int64(world.Dungeon.Depth), int64(world.Turns.Round), int64(world.Kills))
+	write(world.Player.Pos, math.Float32bits(world.Player.Health), world.Player.Stats)
+	writeTurn(&world.Player.Turn)
+	progress := &world.Player.Progress
+	write(int64(progress.Level), int64(progress.Experience), int64(progress.Points))
+	writeEffects(world.Player.Effects)
+	for _, ability := range world.Data.Abilities {
+		write(int64(world.Player.Abilities.Cooldowns[ability.Name]))
+	}
+	for _, flare := range world.Flares {
+		write(flare.Pos, math.Float32bits(flare.Radius), int64(flare.Rounds))
+	}
+	for _, item := range world.Player.Inventory.Items {
+		write([]byte(item.Name), item.Equipped)
+	}
+
+	world.Dungeon.Current().Enemies = world.Enemies
+	world.Dungeon.Current().Items = world.Items
+	for _, floor := range world.Dungeon.Floors {
+		for _, enemy := range floor.Enemies {
+			write([]byte(enemy.Kind), enemy.Pos, math.Float32bits(enemy.Health), enemy.Stats, enemy.LastKnownPlayerPos)
+			writeTurn(&enemy.Turn)
+			writeEffects(enemy.Effects)
+			for _, item := range enemy.Gear {
+				write([]byte(item.Name))
+			}
+		}
+		for _, item := range floor.Items {
+			write([]byte(item.Item.Name), item.Pos)
+		}
+		for _, column := range floor.Map {
+			for _, tile := range column {
+				if tile != nil {
+					write(int64(tile.Type), tile.Block, tile.Explored)
+				}
+			}
+		}
+	}
+	return hash.Sum64()
+}
+
+// RunReplay plays the replay with data without drawing anything, as fast as possible
+func RunReplay(replay *Replay, data *Data) uint64 {
+	config := replay.Config()
+	config.Data = data
+	world := NewWorld(config)
+	input := &ReplayInput{Inputs: replay.Inputs}
+	frontend := &Headless{Input: input}
+
+	for !input.Done() && !world.Player.IsDead() {
+		world.Step(frontend)
+	}
+
+	log.Printf("Replay finished after %d inputs on round %d", input.next, world.Turns.Round)
+	return world.StateHash()
+}
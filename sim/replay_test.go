@@ -0,0 +1,131 @@
+package sim
+
+import (
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInputNames(t *testing.T) {
+	for _, action := range inputActions {
+		input, ok := ParseInput(action.Action.String())
+		if !ok || input != action.Action {
+			t.Errorf("%v doesn't survive a round trip", action.Name)
+		}
+	}
+
+	input := INPUT_LEFT | INPUT_UP | INPUT_END_TURN
+	if parsed, ok := ParseInput(input.String()); !ok || parsed != input {
+		t.Errorf("combined input %q parsed as %q", input, parsed)
+	}
+	if _, ok := ParseInput("left jump"); ok {
+		t.Error("unknown input name accepted")
+	}
+	if recorded := (INPUT_LEFT | INPUT_PAUSE | INPUT_QUICKLOAD | INPUT_VISIBILITY_UP).Recorded(); recorded != INPUT_LEFT {
+		t.Errorf("expected only left to be recorded, got %q", recorded)
+	}
+	playback := ReplayInput{Inputs: []Input{INPUT_LEFT | INPUT_VISIBILITY_DOWN}}
+	if played := playback.Poll(); played != INPUT_LEFT {
+		t.Errorf("expected only left to be played back, got %q", played)
+	}
+}
+
+func TestParseReplay(t *testing.T) {
+	replay, err := ParseReplay("test.replay", `
+version: 1
+seed: 42
+generator: bsp
+size: 40x30
+---
+right
+right down
+
+end-turn
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Seed != 42 || replay.Generator != "bsp" || replay.MapOverrides["size"] != "40x30" {
+		t.Errorf("header parsed wrong: %+v", replay)
+	}
+	expected := []Input{INPUT_RIGHT, INPUT_RIGHT | INPUT_DOWN, INPUT_END_TURN}
+	if len(replay.Inputs) != len(expected) {
+		t.Fatalf("expected %d inputs, got %d", len(expected), len(replay.Inputs))
+	}
+	for i, input := range expected {
+		if replay.Inputs[i] != input {
+			t.Errorf("input %d should be %q, got %q", i, input, replay.Inputs[i])
+		}
+	}
+
+	errors := map[string]string{
+		"version: 2\nseed: 1\n---\n":         "test.replay:1: unsupported replay version",
+		"seed: 1\n":                          "missing \"---\"",
+		"version: 1\n---\n":                  "missing seed header",
+		"seed: 1\n---\nright\nfly\n":         "test.replay:4: unknown input \"fly\"",
+		"seed: 1\nsize: huge\n---\n":         "test.replay:2:",
+		"seed: 1\ngenerator: maze\n---\n":    "unknown generator",
+		"seed: one\n---\n":                   "invalid seed",
+		"seed: 1\nthis is not a header\n---": "expected \"key: value\" header",
+	}
+	for data, expected := range errors {
+		_, err := ParseReplay("test.replay", data)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error containing %q for %q, got %v", expected, data, err)
+		}
+	}
+}
+
+// Inputs that wander around, dig and fight
+func randomInputs(seed int64, count int) []Input {
+	rng := rand.New(rand.NewSource(seed))
+	choices := []Input{
+		INPUT_LEFT, INPUT_RIGHT, INPUT_UP, INPUT_DOWN, INPUT_LEFT | INPUT_UP,
+		INPUT_SELECT, INPUT_DIG, INPUT_ATTACK, INPUT_STAIRS, INPUT_END_TURN, INPUT_END_TURN,
+	}
+	inputs := make([]Input, count)
+	for i := range inputs {
+		inputs[i] = choices[rng.Intn(len(choices))]
+	}
+	return inputs
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	out := log.Writer()
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(out)
+
+	path := filepath.Join(t.TempDir(), "test.replay")
+	config := Config{
+		Seed:         1234,
+		Generator:    "bsp",
+		MapOverrides: map[string]string{"size": "48x32"},
+	}
+
+	world := NewWorld(config)
+	recorder, err := NewReplayRecorder(path, &ReplayInput{Inputs: randomInputs(1, 400)}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frontend := &Headless{Input: recorder}
+	for i := 0; i < 400 && !world.Player.IsDead(); i++ {
+		world.Step(frontend)
+	}
+	recorded := world.StateHash()
+	if world.Turns.Round == 0 {
+		t.Fatal("the recorded run never ended a turn")
+	}
+
+	replay, err := LoadReplay(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for run := 0; run < 2; run++ {
+		if hash := RunReplay(replay, world.Data); hash != recorded {
+			t.Fatalf("replay %d ended with hash %016x, the recording with %016x", run, hash, recorded)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package sim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Bump when the save format changes and add a migration from the previous version
+const SAVE_VERSION = 10
+
+// Migrations upgrade the raw JSON of a save from the version in the key to the next one,
+// so old saves keep loading after the format changes. Numbers in the raw save are json.Number.
+var saveMigrations = map[int]func(save map[string]interface{}) error{
+	// Version 1 reseeded the random numbers on every save, continuing from the start of that seed is the same
+	1: func(save map[string]interface{}) error {
+		save["rngCalls"] = json.Number("0")
+		return nil
+	},
+	// Version 2 saved the whole UI state, only the selection cursor is part of the game
+	2: func(save map[string]interface{}) error {
+		if uiState, ok := save["uiState"].(map[string]interface{}); ok {
+			save["selection"] = uiState["SelectionMode"]
+		}
+		delete(save, "uiState")
+		return nil
+	},
+	// Version 3 only had goblins, drawn by their State
+	3: func(save map[string]interface{}) error {
+		if player, ok := save["player"].(map[string]interface{}); ok {
+			delete(player, "State")
+		}
+		floors, _ := save["floors"].([]interface{})
+		for _, rawFloor := range floors {
+			floor, _ := rawFloor.(map[string]interface{})
+			enemies, _ := floor["enemies"].([]interface{})
+			for _, rawEnemy := range enemies {
+				if enemy, ok := rawEnemy.(map[string]interface{}); ok {
+					delete(enemy, "State")
+					enemy["Kind"] = goblinArchetype.Name
+					enemy["Sprite"] = goblinArchetype.Sprite
+					enemy["Behaviour"] = goblinArchetype.Behaviour
+				}
+			}
+		}
+		return nil
+	},
+	// Version 4 enemies didn't remember where they spawned or their max health, start from where they are now
+	4: func(save map[string]interface{}) error {
+		floors, _ := save["floors"].([]interface{})
+		for _, rawFloor := range floors {
+			floor, _ := rawFloor.(map[string]interface{})
+			enemies, _ := floor["enemies"].([]interface{})
+			for _, rawEnemy := range enemies {
+				if enemy, ok := rawEnemy.(map[string]interface{}); ok {
+					enemy["Home"] = enemy["Pos"]
+					enemy["MaxHealth"] = enemy["Health"]
+				}
+			}
+		}
+		return nil
+	},
+	// Version 5 had no items, the player starts with empty hands and the floors have nothing lying around
+	5: func(save map[string]interface{}) error {
+		if player, ok := save["player"].(map[string]interface{}); ok {
+			player["Inventory"] = map[string]interface{}{"Items": []interface{}{}, "Selected": json.Number("0")}
+		}
+		return nil
+	},
+	// Version 6 had nothing to equip, the items carried then stay unequipped and enemies go without gear
+	6: func(save map[string]interface{}) error {
+		floors, _ := save["floors"].([]interface{})
+		for _, rawFloor := range floors {
+			floor, _ := rawFloor.(map[string]interface{})
+			enemies, _ := floor["enemies"].([]interface{})
+			for _, rawEnemy := range enemies {
+				if enemy, ok := rawEnemy.(map[string]interface{}); ok {
+					enemy["Gear"] = []interface{}{}
+				}
+			}
+		}
+		return nil
+	},
+	// Version 7 had no experience, the player starts again from the first level
+	7: func(save map[string]interface{}) error {
+		if player, ok := save["player"].(map[string]interface{}); ok {
+			player["Progress"] = map[string]interface{}{"Level": json.Number("1")}
+		}
+		return nil
+	},
+	// Version 8 had no effects, nobody is poisoned or stunned
+	8: func(save map[string]interface{}) error {
+		if player, ok := save["player"].(map[string]interface{}); ok {
+			player["Effects"] = []interface{}{}
+		}
+		floors, _ := save["floors"].([]interface{})
+		for _, rawFloor := range floors {
+			floor, _ := rawFloor.(map[string]interface{})
+			enemies, _ := floor["enemies"].([]interface{})
+			for _, rawEnemy := range enemies {
+				if enemy, ok := rawEnemy.(map[string]interface{}); ok {
+					enemy["Effects"] = []interface{}{}
+				}
+			}
+		}
+		return nil
+	},
+	// Version 9 had no abilities, everything on the hotbar is ready and nothing is lit
+	9: func(save map[string]interface{}) error {
+		if player, ok := save["player"].(map[string]interface{}); ok {
+			player["Abilities"] = map[string]interface{}{"Selected": json.Number("0"), "Cooldowns": map[string]interface{}{}}
+		}
+		save["flares"] = []interface{}{}
+		return nil
+	},
+}
+
+type SaveFile struct {
+	Version   int           `json:"version"`
+	Seed      int64         `json:"seed"`
+	RngSeed   int64         `json:"rngSeed"`
+	RngCalls  uint64        `json:"rngCalls"`
+	TileSet   string        `json:"tileSet"`
+	Kills     int           `json:"kills"`
+	Round     int           `json:"round"`
+	Player    Player        `json:"player"`
+	Selection SelectionMode `json:"selection"`
+	Flares    []*Flare      `json:"flares"`
+	Level     *LevelFile    `json:"level"`
+	Depth     int           `json:"depth"`
+	Floors    []SaveFloor   `json:"floors"`
+}
+
+type SaveFloor struct {
+	Depth   int           `json:"depth"`
+	Seed    int64         `json:"seed"`
+	Entry   IVector2      `json:"entry"`
+	Exit    IVector2      `json:"exit"`
+	HasExit bool          `json:"hasExit"`
+	Enemies []*Enemy      `json:"enemies"`
+	Items   []*FloorItem  `json:"items"`
+	Tiles   [][]*SaveTile `json:"tiles"`
+}
+
+// Tiles are the bulk of a save, positions come from the index and the rest is recomputed every frame
+type SaveTile struct {
+	Type           int  `json:"t"`
+	Block          bool `json:"b,omitempty"`
+	Explored       bool `json:"e,omitempty"`
+	RememberedType int  `json:"r,omitempty"`
+}
+
+func (world *World) SaveGame(path string) error {
+	t := time.Now()
+	data, err := json.Marshal(world.newSaveFile())
+	if err != nil {
+		return err
+	}
+
+	// Write next to the old save first so a crash can't leave a half written file behind
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	log.Printf("Saved game to %v in %v", path, time.Since(t))
+	return nil
+}
+
+// LoadGame continues the saved game with data, which should be what it was started with
+func LoadGame(path string, data *Data) (*World, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	save, err := ParseSave(contents)
+	if err == nil {
+		err = save.check()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	world := restoreSave(save, data)
+	log.Printf("Loaded game from %v", path)
+	return world, nil
+}
+
+// ParseSave migrates the save to the current version before decoding it
+func ParseSave(data []byte) (*SaveFile, error) {
+	// Numbers stay as text, seeds don't fit in a float64
+	var raw map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	number, ok := raw["version"].(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("save has no version")
+	}
+	version, err := number.Int64()
+	if err != nil {
+		return nil, fmt.Errorf("invalid save version %v", number)
+	}
+	if err := migrateSave(raw, int(version), SAVE_VERSION); err != nil {
+		return nil, err
+	}
+
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var save SaveFile
+	if err := json.Unmarshal(migrated, &save); err != nil {
+		return nil, err
+	}
+	return &save, nil
+}
+
+// check catches what a corrupt or hand-edited save would crash the game with
+func (save *SaveFile) check() error {
+	if save.Depth < 0 || save.Depth >= len(save.Floors) {
+		return fmt.Errorf("save is on floor %d but has %d floors", save.Depth+1, len(save.Floors))
+	}
+	return nil
+}
+
+func migrateSave(raw map[string]interface{}, from int, to int) error {
+	if from > to {
+		return fmt.Errorf("save version %d is newer than the supported version %d", from, to)
+	}
+
+	for version := from; version < to; version++ {
+		migration, ok := saveMigrations[version]
+		if !ok {
+			return fmt.Errorf("no migration from save version %d", version)
+		}
+		if err := migration(raw); err != nil {
+			return fmt.Errorf("migrating save version %d: %v", version, err)
+		}
+		raw["version"] = json.Number(strconv.Itoa(version + 1))
+		log.Printf("Migrated save from version %d to %d", version, version+1)
+	}
+	return nil
+}
+
+func DeleteSave(path string) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Couldn't delete save %v: %v", path, err)
+	}
+}
+
+func (world *World) newSaveFile() *SaveFile {
+	rngSeed, rngCalls := world.rngSource.State()
+
+	// Enemies and items may have been removed from the slices since the floor was entered
+	world.Dungeon.Current().Enemies = world.Enemies
+	world.Dungeon.Current().Items = world.Items
+
+	save := SaveFile{
+		Version:   SAVE_VERSION,
+		Seed:      world.Seed,
+		RngSeed:   rngSeed,
+		RngCalls:  rngCalls,
+		TileSet:   world.TileSet,
+		Kills:     world.Kills,
+		Round:     world.Turns.Round,
+		Player:    *world.Player,
+		Selection: world.Selection,
+		Flares:    world.Flares,
+		Level:     world.Dungeon.Level,
+		Depth:     world.Dungeon.Depth,
+	}
+
+	for _, floor := range world.Dungeon.Floors {
+		saveFloor := SaveFloor{
+			Depth:   floor.Depth,
+			Seed:    floor.Seed,
+			Entry:   floor.Entry,
+			Exit:    floor.Exit,
+			HasExit: floor.HasExit,
+			Enemies: floor.Enemies,
+			Items:   floor.Items,
+			Tiles:   make([][]*SaveTile, len(floor.Map)),
+		}
+		for x, column := range floor.Map {
+			saveFloor.Tiles[x] = make([]*SaveTile, len(column))
+			for y, tile := range column {
+				if tile != nil {
+					saveFloor.Tiles[x][y] = &SaveTile{
+						Type:           tile.Type,
+						Block:          tile.Block,
+						Explored:       tile.Explored,
+						RememberedType: tile.RememberedType,
+					}
+				}
+			}
+		}
+		save.Floors = append(save.Floors, saveFloor)
+	}
+	return &save
+}
+
+func restoreSave(save *SaveFile, data *Data) *World {
+	player := save.Player
+	world := World{
+		Player:    &player,
+		Data:      data,
+		Selection: save.Selection,
+		Flares:    save.Flares,
+		Seed:      save.Seed,
+		TileSet:   save.TileSet,
+		Kills:     save.Kills,
+		Turns:     TurnScheduler{Round: save.Round},
+	}
+	world.restoreRng(save.RngSeed, save.RngCalls)
+
+	dungeon := Dungeon{
+		Seed:  save.Seed,
+		Level: save.Level,
+		Depth: save.Depth,
+		data:  data,
+	}
+	for _, saveFloor := range save.Floors {
+		floor := Floor{
+			Depth:   saveFloor.Depth,
+			Seed:    saveFloor.Seed,
+			Entry:   saveFloor.Entry,
+			Exit:    saveFloor.Exit,
+			HasExit: saveFloor.HasExit,
+			Enemies: saveFloor.Enemies,
+			Items:   saveFloor.Items,
+			Map:     make([][]*Tile, len(saveFloor.Tiles)),
+		}
+		for x, column := range saveFloor.Tiles {
+			floor.Map[x] = make([]*Tile, len(column))
+			for y, saveTile := range column {
+				if saveTile != nil {
+					floor.Map[x][y] = &Tile{
+						Type:           saveTile.Type,
+						Pos:            NewIVector2(int32(x)*TILE_SIZE, int32(y)*TILE_SIZE),
+						Block:          saveTile.Block,
+						Explored:       saveTile.Explored,
+						RememberedType: saveTile.RememberedType,
+					}
+				}
+			}
+		}
+		dungeon.Floors = append(dungeon.Floors, &floor)
+	}
+	world.Dungeon = &dungeon
+	world.Map = dungeon.Current().Map
+	world.Enemies = dungeon.Current().Enemies
+	world.Items = dungeon.Current().Items
+	return &world
+}
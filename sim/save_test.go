@@ -0,0 +1,271 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newTestDungeon(t *testing.T) *World {
+	world := newTestWorld(t, 7, turnTestMap)
+	level := &LevelFile{Name: "test", Config: DefaultGeneratorConfig()}
+	world.Dungeon = &Dungeon{
+		Seed:   7,
+		Level:  level,
+		Floors: []*Floor{{Map: world.Map, Enemies: world.Enemies, Entry: world.Player.Pos}},
+		data:   world.Data,
+	}
+	// Seeds from the clock don't fit in a float64
+	world.Seed = 1634554829123456789
+	return world
+}
+
+func saveAndLoad(t *testing.T, world *World) *SaveFile {
+	data, err := json.Marshal(world.newSaveFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	save, err := ParseSave(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return save
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	world := newTestDungeon(t)
+	for round := 0; round < 3; round++ {
+		world.EndTurn()
+		world.Turns.RunEnemyPhase()
+	}
+	world.Map[3][1].Destroy()
+	world.Map[2][2].Remember()
+	world.Enemies = world.Enemies[1:]
+	world.Kills = 1
+	world.Selection = SelectionMode{Using: true, Pos: NewIVector2(2*TILE_SIZE, 2*TILE_SIZE)}
+	world.Player.Inventory.Items = []*Item{
+		{Name: "pick", Category: ITEM_DIGGING_TOOL, Weight: 4},
+		{Name: "sword", Category: ITEM_WEAPON, Weight: 3, Equipped: true, Damage: Dice{Count: 1, Sides: 6, Bonus: 1}, Bonus: StatBonus{Dexterity: -1}},
+	}
+	world.Player.Progress = Progress{Level: 3, Experience: 170, Points: 1, Selected: 2}
+	world.Player.Effects = Effects{{Kind: EFFECT_POISON, Turns: 2, Stacks: 3}, {Kind: EFFECT_HASTE, Turns: 1, Stacks: 1}}
+	world.Player.Abilities = AbilityBar{Selected: 1, Cooldowns: map[string]int{"dash": 2}}
+	world.Flares = []*Flare{{Pos: tilePos(2, 2), Radius: 4, Rounds: 2}}
+	world.Items = []*FloorItem{{Pos: tilePos(2, 1), Item: &Item{Name: "bread", Category: ITEM_CONSUMABLE, Weight: 0.5, Heal: 5}}}
+
+	beforeSelection := world.Selection
+	beforePlayer := *world.Player
+	var beforeEnemies []Enemy
+	for _, enemy := range world.Enemies {
+		beforeEnemies = append(beforeEnemies, *enemy)
+	}
+
+	save := saveAndLoad(t, world)
+	nextBefore := world.rng.Int63()
+	loaded := restoreSave(save, world.Data)
+
+	if !reflect.DeepEqual(*loaded.Player, beforePlayer) {
+		t.Errorf("player changed from %+v to %+v", beforePlayer, *loaded.Player)
+	}
+	if len(loaded.Items) != 1 || !reflect.DeepEqual(*loaded.Items[0], *world.Items[0]) {
+		t.Errorf("floor items changed from %v to %v", world.Items, loaded.Items)
+	}
+	if len(loaded.Enemies) != len(beforeEnemies) {
+		t.Fatalf("expected %d enemies, got %d", len(beforeEnemies), len(loaded.Enemies))
+	}
+	for i, enemy := range loaded.Enemies {
+		if enemy.Pos != beforeEnemies[i].Pos || enemy.Health != beforeEnemies[i].Health ||
+			enemy.Turn != beforeEnemies[i].Turn || enemy.LastKnownPlayerPos != beforeEnemies[i].LastKnownPlayerPos {
+			t.Errorf("enemy %d changed from %+v to %+v", i, beforeEnemies[i], *enemy)
+		}
+	}
+	for x, column := range world.Map {
+		for y, tile := range column {
+			loadedTile := loaded.Map[x][y]
+			if tile.Type != loadedTile.Type || tile.Block != loadedTile.Block || tile.Pos != loadedTile.Pos ||
+				tile.Explored != loadedTile.Explored || tile.RememberedType != loadedTile.RememberedType {
+				t.Fatalf("tile %d,%d changed from %+v to %+v", x, y, *tile, *loadedTile)
+			}
+		}
+	}
+	if len(loaded.Flares) != 1 || !reflect.DeepEqual(*loaded.Flares[0], *world.Flares[0]) {
+		t.Errorf("flares changed from %v to %v", world.Flares, loaded.Flares)
+	}
+	if loaded.Kills != 1 || loaded.Turns.Round != 3 || loaded.Selection != beforeSelection || loaded.Seed != 1634554829123456789 {
+		t.Error("game state wasn't restored")
+	}
+	if next := loaded.rng.Int63(); next != nextBefore {
+		t.Error("random numbers differ after loading")
+	}
+}
+
+func TestSaveMigrations(t *testing.T) {
+	saveMigrations[0] = func(save map[string]interface{}) error {
+		save["kills"] = save["killCount"]
+		delete(save, "killCount")
+		return nil
+	}
+	defer delete(saveMigrations, 0)
+
+	raw := map[string]interface{}{"version": json.Number("0"), "killCount": json.Number("4")}
+	if err := migrateSave(raw, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if raw["kills"] != json.Number("4") || raw["version"] != json.Number("1") {
+		t.Errorf("migration wasn't applied: %v", raw)
+	}
+
+	if err := migrateSave(raw, 1, SAVE_VERSION+1); err == nil {
+		t.Error("missing migration wasn't reported")
+	}
+	if err := migrateSave(raw, SAVE_VERSION+1, SAVE_VERSION); err == nil {
+		t.Error("newer save version wasn't reported")
+	}
+}
+
+func TestSaveVersion1(t *testing.T) {
+	save, err := ParseSave([]byte(`{"version": 1, "seed": 1634554829123456789, "rngSeed": 99, "player": {"Health": 12}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if save.Version != SAVE_VERSION || save.RngSeed != 99 || save.RngCalls != 0 || save.Player.Health != 12 || save.Seed != 1634554829123456789 {
+		t.Errorf("version 1 save migrated wrong: %+v", save)
+	}
+}
+
+func TestSaveVersion2(t *testing.T) {
+	save, err := ParseSave([]byte(`{"version": 2, "seed": 3, "uiState": {"CharacterPanelOpen": true, "SelectionMode": {"Using": true, "Pos": {"X": 64, "Y": 32}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if save.Selection != (SelectionMode{Using: true, Pos: NewIVector2(64, 32)}) {
+		t.Errorf("selection wasn't moved out of the UI state: %+v", save.Selection)
+	}
+}
+
+func TestSaveVersion3(t *testing.T) {
+	save, err := ParseSave([]byte(`{"version": 3, "seed": 3, "player": {"State": 0}, "floors": [{"enemies": [{"State": 1, "Health": 7}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enemy := save.Floors[0].Enemies[0]
+	if enemy.Kind != "goblin" || enemy.Sprite != goblinArchetype.Sprite || enemy.Behaviour != DEFAULT_BEHAVIOUR || enemy.Health != 7 {
+		t.Errorf("enemy migrated wrong: %+v", *enemy)
+	}
+}
+
+func TestSaveVersion4(t *testing.T) {
+	save, err := ParseSave([]byte(`{"version": 4, "seed": 3, "floors": [{"enemies": [{"Kind": "goblin", "Pos": {"X": 64, "Y": 32}, "Health": 7}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enemy := save.Floors[0].Enemies[0]
+	if enemy.Home != NewIVector2(64, 32) || enemy.MaxHealth != 7 {
+		t.Errorf("enemy migrated wrong: %+v", *enemy)
+	}
+}
+
+func TestSaveVersion5(t *testing.T) {
+	save, err := ParseSave([]byte(`{"version": 5, "seed": 3, "player": {"Health": 10}, "floors": [{"enemies": []}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(save.Player.Inventory.Items) != 0 || save.Player.Health != 10 || len(save.Floors[0].Items) != 0 {
+		t.Errorf("player migrated wrong: %+v", save.Player)
+	}
+}
+
+func TestSaveVersion6(t *testing.T) {
+	save, err := ParseSave([]byte(`{"version": 6, "seed": 3, "player": {"Inventory": {"Items": [{"name": "dagger", "category": "weapon"}]}}, "floors": [{"enemies": [{"Kind": "goblin"}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item := save.Player.Inventory.Items[0]; item.Equipped || !item.Damage.IsZero() {
+		t.Errorf("item migrated wrong: %+v", *item)
+	}
+	if enemy := save.Floors[0].Enemies[0]; enemy.Gear == nil || len(enemy.Gear) != 0 {
+		t.Errorf("enemy migrated wrong: %+v", *enemy)
+	}
+}
+
+func TestSaveVersion7(t *testing.T) {
+	save, err := ParseSave([]byte(`{"version": 7, "seed": 3, "player": {"Stats": {"Strength": 6}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress := save.Player.Progress; progress != (Progress{Level: 1}) {
+		t.Errorf("progress migrated wrong: %+v", progress)
+	}
+}
+
+func TestSaveVersion8(t *testing.T) {
+	save, err := ParseSave([]byte(`{"version": 8, "seed": 3, "player": {"Progress": {"Level": 2}}, "floors": [{"enemies": [{"Kind": "goblin", "Gear": []}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if save.Player.Effects == nil || len(save.Player.Effects) != 0 {
+		t.Errorf("player effects migrated wrong: %v", save.Player.Effects)
+	}
+	if enemy := save.Floors[0].Enemies[0]; enemy.Effects == nil || len(enemy.Effects) != 0 {
+		t.Errorf("enemy migrated wrong: %+v", *enemy)
+	}
+}
+
+func TestSaveVersion9(t *testing.T) {
+	save, err := ParseSave([]byte(`{"version": 9, "seed": 3, "player": {"Effects": []}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bar := save.Player.Abilities; bar.Selected != 0 || bar.Cooldowns == nil || len(bar.Cooldowns) != 0 {
+		t.Errorf("hotbar migrated wrong: %+v", bar)
+	}
+	if save.Flares == nil || len(save.Flares) != 0 {
+		t.Errorf("flares migrated wrong: %v", save.Flares)
+	}
+}
+
+func TestRandomSourceRestore(t *testing.T) {
+	source := NewRandomSource(5, 0)
+	rng := rand.New(source)
+	for i := 0; i < 100; i++ {
+		rng.Intn(10)
+		rng.Float64()
+	}
+
+	seed, calls := source.State()
+	restored := rand.New(NewRandomSource(seed, calls))
+	for i := 0; i < 100; i++ {
+		if a, b := rng.Int63(), restored.Int63(); a != b {
+			t.Fatalf("draw %d differs after restoring: %d and %d", i, a, b)
+		}
+	}
+}
+
+func TestParseSaveErrors(t *testing.T) {
+	for _, data := range []string{"", "{}", `{"version": "one"}`, `{"version": 99}`} {
+		if _, err := ParseSave([]byte(data)); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
+
+func TestLoadGameChecksFloors(t *testing.T) {
+	cases := map[string]string{
+		"no floors":      `{"version": %d, "seed": 3, "floors": []}`,
+		"too deep":       `{"version": %d, "seed": 3, "depth": 1, "floors": [{"depth": 0}]}`,
+		"negative depth": `{"version": %d, "seed": 3, "depth": -1, "floors": [{"depth": 0}]}`,
+	}
+	for name, data := range cases {
+		path := filepath.Join(t.TempDir(), "save.json")
+		if err := ioutil.WriteFile(path, []byte(fmt.Sprintf(data, SAVE_VERSION)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadGame(path, DefaultData()); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
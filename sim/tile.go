@@ -0,0 +1,179 @@
+package sim
+
+import (
+	"math/rand"
+)
+
+const TILE_SIZE int32 = 32
+
+const (
+	TILE_FLOOR_STONE    = iota
+	TILE_WALL_STONE     = iota
+	TILE_WALL_MOSS      = iota
+	TILE_FLOOR_SPAWN    = iota
+	TILE_FLOOR_OBS      = iota
+	TILE_FLOOR_STONE_BL = iota
+	TILE_STAIRS_UP      = iota
+	TILE_STAIRS_DOWN    = iota
+)
+
+type Tile struct {
+	Type       int
+	Pos        IVector2
+	Block      bool
+	Neighbours uint16
+	LightLevel uint8
+
+	// Explored tiles stay on screen after leaving the player's sight,
+	// drawn as they were when last seen
+	Explored       bool
+	RememberedType int
+}
+
+// Neighbours is a bitmask of the surrounding tiles of another type, used to pick the wall texture
+func (tile *Tile) UpdateNeighbours(world *World) {
+	count := uint16(0)
+	if nb, ok := world.GetMapTile(NewIVector2(tile.Pos.X, tile.Pos.Y-TILE_SIZE)); ok && nb.Type != tile.Type {
+		count += 1
+	}
+	if nb, ok := world.GetMapTile(NewIVector2(tile.Pos.X+TILE_SIZE, tile.Pos.Y)); ok && nb.Type != tile.Type {
+		count += 8
+	}
+	if nb, ok := world.GetMapTile(NewIVector2(tile.Pos.X, tile.Pos.Y+TILE_SIZE)); ok && nb.Type != tile.Type {
+		count += 64
+	}
+	if nb, ok := world.GetMapTile(NewIVector2(tile.Pos.X-TILE_SIZE, tile.Pos.Y)); ok && nb.Type != tile.Type {
+		count += 512
+	}
+
+	if nb, ok := world.GetMapTile(NewIVector2(tile.Pos.X+TILE_SIZE, tile.Pos.Y-TILE_SIZE)); ok && nb.Type != tile.Type {
+		if count&1 > 0 && count&8 > 0 {
+			count += 2
+		} else {
+			count += 4
+		}
+	}
+	if nb, ok := world.GetMapTile(NewIVector2(tile.Pos.X+TILE_SIZE, tile.Pos.Y+TILE_SIZE)); ok && nb.Type != tile.Type {
+		if count&8 > 0 && count&64 > 0 {
+			count += 16
+		} else {
+			count += 32
+		}
+	}
+	if nb, ok := world.GetMapTile(NewIVector2(tile.Pos.X-TILE_SIZE, tile.Pos.Y+TILE_SIZE)); ok && nb.Type != tile.Type {
+		if count&64 > 0 && count&512 > 0 {
+			count += 128
+		} else {
+			count += 256
+		}
+	}
+	if nb, ok := world.GetMapTile(NewIVector2(tile.Pos.X-TILE_SIZE, tile.Pos.Y-TILE_SIZE)); ok && nb.Type != tile.Type {
+		if count&512 > 0 && count&1 > 0 {
+			count += 1024
+		} else {
+			count += 2048
+		}
+	}
+
+	tile.Neighbours = count
+}
+
+func (tile *Tile) Remember() {
+	tile.Explored = true
+	tile.RememberedType = tile.Type
+}
+
+func (tile *Tile) Destroy() bool {
+	if tile.Block {
+		tile.Type = TILE_FLOOR_STONE
+		tile.Block = false
+		return true
+	}
+	return false
+}
+
+func (tile *Tile) DistanceToPlayer(world *World) float32 {
+	return distance(tile.Pos, world.Player.Pos)
+}
+
+func (tile *Tile) DistanceToEnemy(enemy *Enemy) float32 {
+	return distance(tile.Pos, enemy.Pos)
+}
+
+// Also lights the tile by the player, their flares and the enemies they see
+func (tile *Tile) VisibleToPlayer(world *World) bool {
+	if !world.PlayerSight[tilePoint(tile.Pos)] {
+		tile.LightLevel = 0
+		return false
+	}
+
+	distance := tile.DistanceToPlayer(world)
+	tile.LightLevel = calculateLightLevel(distance, world.Player.EffectiveStats().Visibility)
+	if light := world.flareLight(tile.Pos); light > tile.LightLevel {
+		tile.LightLevel = light
+	}
+	for _, enemy := range world.VisibleEnemies {
+		if nlight := enemy.LightEmittedToTile(tile); nlight > tile.LightLevel {
+			tile.LightLevel = nlight
+		}
+	}
+
+	return tile.LightLevel > 0
+}
+
+func charToTile(c string, pos IVector2, rng *rand.Rand) Tile {
+	switch c {
+	case "@":
+		return Tile{
+			Type:  TILE_WALL_STONE,
+			Pos:   pos,
+			Block: true,
+		}
+	case "_":
+		ti := TILE_FLOOR_STONE
+		if rng.Float32() < 0.01 {
+			ti = TILE_FLOOR_STONE_BL
+		}
+		return Tile{
+			Type:  ti,
+			Pos:   pos,
+			Block: false,
+		}
+	case "!":
+		return Tile{
+			Type:  TILE_WALL_MOSS,
+			Pos:   pos,
+			Block: true,
+		}
+	case "P":
+		return Tile{
+			Type:  TILE_FLOOR_SPAWN,
+			Pos:   pos,
+			Block: false,
+		}
+	case "-":
+		return Tile{
+			Type:  TILE_FLOOR_OBS,
+			Pos:   pos,
+			Block: false,
+		}
+	case "<":
+		return Tile{
+			Type:  TILE_STAIRS_UP,
+			Pos:   pos,
+			Block: false,
+		}
+	case ">":
+		return Tile{
+			Type:  TILE_STAIRS_DOWN,
+			Pos:   pos,
+			Block: false,
+		}
+	default:
+		return Tile{
+			Type:  TILE_FLOOR_STONE,
+			Pos:   pos,
+			Block: false,
+		}
+	}
+}
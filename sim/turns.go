@@ -0,0 +1,181 @@
+package sim
+
+import (
+	"log"
+	"time"
+)
+
+// Energy a character spends to take a turn
+const TURN_ENERGY = 100
+
+// Safety net for enemies that keep trying moves that never succeed, like a random walk boxed in by walls
+const MAX_ENEMY_ACTIONS_PER_TURN = 64
+
+// Number of upcoming turns shown in the turn order bar
+const TURN_ORDER_LENGTH = 8
+
+// TurnScheduler decides who acts next using an energy system.
+// Every character gains energy by its Speed until one of them has enough to act,
+// so a character twice as fast as the player gets two turns for every one of theirs
+// and a slow one now and then skips a round.
+// Enemies finish their whole turn before the next character starts.
+type TurnScheduler struct {
+	Round int
+
+	world   *World
+	current *Enemy
+	actions int
+}
+
+// Energy gained per tick of the scheduler
+func Speed(stats *Stats) int {
+	speed := int(stats.Dexterity) + int(stats.Movement)
+	if speed < 1 {
+		return 1
+	}
+	return speed
+}
+
+// Breaks ties between characters with the same energy, higher acts earlier
+func Initiative(stats *Stats) int {
+	return int(stats.Dexterity)*2 + int(stats.Movement)
+}
+
+// Ends the player's turn and hands out turns to the world's enemies until the player can act again
+func (scheduler *TurnScheduler) BeginEnemyPhase(world *World) {
+	scheduler.world = world
+	scheduler.current = nil
+	world.Player.Turn.Energy -= TURN_ENERGY
+	scheduler.advance()
+}
+
+func (scheduler *TurnScheduler) EnemyPhaseDone() bool {
+	return scheduler.current == nil
+}
+
+// Current returns the enemy whose turn it is, nil once the phase is over
+func (scheduler *TurnScheduler) Current() *Enemy {
+	return scheduler.current
+}
+
+// Step runs a single action of the current enemy and moves on to the next character when its turn is done
+func (scheduler *TurnScheduler) Step() {
+	enemy := scheduler.current
+	if enemy == nil {
+		return
+	}
+
+	player := scheduler.world.Player
+	if !enemy.Turn.Done && enemy.Health > 0.0 && !player.IsDead() {
+		enemy.DoAction(scheduler.world)
+		scheduler.actions++
+		if scheduler.actions < MAX_ENEMY_ACTIONS_PER_TURN {
+			return
+		}
+		enemy.Turn.Done = true
+	}
+
+	if player.IsDead() {
+		scheduler.current = nil
+		return
+	}
+	scheduler.world.endTurn(enemy)
+	scheduler.advance()
+}
+
+// RunEnemyPhase plays out every remaining enemy turn and starts the player's next turn
+func (scheduler *TurnScheduler) RunEnemyPhase() {
+	t := time.Now()
+	for !scheduler.EnemyPhaseDone() {
+		scheduler.Step()
+	}
+	log.Printf("Enemy turns of round %d processed in %v", scheduler.Round, time.Since(t))
+
+	scheduler.Round++
+	scheduler.world.reseedRng()
+	scheduler.world.tickAbilities()
+	player := scheduler.world.Player
+	scheduler.world.startTurn(player)
+	scheduler.world.emit(EVENT_ROUND_ENDED, player.Pos, "Round %d ended", scheduler.Round)
+}
+
+// Gives out energy until the next character can act and starts an enemy's turn if it is theirs
+func (scheduler *TurnScheduler) advance() {
+	characters := turnCharacters(scheduler.world.Player, scheduler.world.Enemies)
+	energy := make([]int, len(characters))
+	for i, character := range characters {
+		energy[i] = character.GetTurn().Energy
+	}
+
+	next := nextTurn(characters, energy)
+	for i, character := range characters {
+		character.GetTurn().Energy = energy[i]
+	}
+
+	scheduler.actions = 0
+	if next == 0 {
+		scheduler.current = nil
+		return
+	}
+	scheduler.current = characters[next].(*Enemy)
+	scheduler.world.startTurn(scheduler.current)
+	scheduler.current.Turn.Energy -= TURN_ENERGY
+}
+
+// TurnOrder predicts who acts next, starting with the player whose turn it currently is
+func TurnOrder(player *Player, enemies []*Enemy, count int) []Character {
+	characters := turnCharacters(player, enemies)
+	energy := make([]int, len(characters))
+	for i, character := range characters {
+		energy[i] = character.GetTurn().Energy
+	}
+	energy[0] -= TURN_ENERGY
+
+	order := []Character{player}
+	for len(order) < count {
+		next := nextTurn(characters, energy)
+		energy[next] -= TURN_ENERGY
+		order = append(order, characters[next])
+	}
+	return order
+}
+
+// The player always comes first, dead enemies don't take turns
+func turnCharacters(player *Player, enemies []*Enemy) []Character {
+	characters := []Character{player}
+	for _, enemy := range enemies {
+		if enemy.Health > 0.0 {
+			characters = append(characters, enemy)
+		}
+	}
+	return characters
+}
+
+// Returns the index of the character that acts next, adding to energy until someone has enough.
+// The one with the most energy goes first, then the one with the higher initiative.
+func nextTurn(characters []Character, energy []int) int {
+	for {
+		next := -1
+		for i, character := range characters {
+			if energy[i] < TURN_ENERGY {
+				continue
+			}
+			if next < 0 || energy[i] > energy[next] {
+				next = i
+			} else if energy[i] == energy[next] {
+				stats, nextStats := character.EffectiveStats(), characters[next].EffectiveStats()
+				if Initiative(&stats) > Initiative(&nextStats) {
+					next = i
+				}
+			}
+		}
+		if next >= 0 {
+			return next
+		}
+
+		for i, character := range characters {
+			stats := character.EffectiveStats()
+			energy[i] += Speed(&stats)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package sim
+
+import (
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Sets up a world from a map where 'P' is the player and 'g' a goblin
+func newTestWorld(t *testing.T, seed int64, rows string) *World {
+	out := log.Writer()
+	log.SetOutput(ioutil.Discard)
+	t.Cleanup(func() { log.SetOutput(out) })
+
+	rng := rand.New(rand.NewSource(seed))
+	mapstring := strings.Trim(rows, "\n")
+	var enemies []*Enemy
+	for y, row := range strings.Split(mapstring, "\n") {
+		for x, glyph := range row {
+			if glyph == 'g' {
+				enemies = append(enemies, goblinArchetype.NewEnemy(NewIVector2(int32(x)*TILE_SIZE, int32(y)*TILE_SIZE), 0))
+			}
+		}
+	}
+	tiles, spawn := generateTiles(strings.ReplaceAll(mapstring, "g", "_"), rng)
+
+	data := DefaultData()
+	player := NewPlayer()
+	player.Pos = spawn
+	world := World{
+		Player:    player,
+		Map:       tiles,
+		Enemies:   enemies,
+		Data:      data,
+		Dungeon:   &Dungeon{Floors: []*Floor{{Map: tiles, Enemies: enemies, Entry: spawn}}, data: data},
+		rngSource: NewRandomSource(seed, 0),
+	}
+	world.rng = rand.New(world.rngSource)
+	return &world
+}
+
+func enemyPositions(world *World) []IVector2 {
+	var positions []IVector2
+	for _, enemy := range world.Enemies {
+		positions = append(positions, enemy.Pos)
+	}
+	return positions
+}
+
+const turnTestMap = `
+@@@@@@@@@@@@@@@@
+@P_____@_______@
+@______@___g___@
+@___@@@@_______@
+@_____________g@
+@__g____@@@@___@
+@@@@@@@@@@@@@@@@
+`
+
+func TestEnemyPhaseFinishesEveryEnemy(t *testing.T) {
+	world := newTestWorld(t, 1, turnTestMap)
+
+	world.EndTurn()
+	world.Turns.RunEnemyPhase()
+
+	for i, enemy := range world.Enemies {
+		if !enemy.Turn.Done {
+			t.Errorf("enemy %d hasn't finished its turn", i)
+		}
+	}
+	if world.Player.Turn.Done {
+		t.Error("the player's turn didn't start after the enemy phase")
+	}
+	if world.Turns.Round != 1 {
+		t.Errorf("expected round 1, got %d", world.Turns.Round)
+	}
+}
+
+func TestEnemyPhaseWaitsForSlowEnemies(t *testing.T) {
+	world := newTestWorld(t, 1, turnTestMap)
+	for _, enemy := range world.Enemies {
+		enemy.Stats = world.Player.Stats
+	}
+	world.EndTurn()
+
+	// The first enemy finishing must not end the phase for the others
+	first := world.Turns.Current()
+	if first == nil {
+		t.Fatal("no enemy got a turn")
+	}
+	for world.Turns.Current() == first {
+		world.Turns.Step()
+	}
+	if world.Turns.EnemyPhaseDone() {
+		t.Fatal("phase ended after the first enemy")
+	}
+	if !first.Turn.Done {
+		t.Error("moved on before the first enemy was done")
+	}
+}
+
+// Plays out an enemy phase and returns the enemies in the order they took their turns
+func runEnemyPhase(world *World) []*Enemy {
+	var order []*Enemy
+	world.EndTurn()
+	for !world.Turns.EnemyPhaseDone() {
+		if world.Turns.actions == 0 {
+			order = append(order, world.Turns.Current())
+		}
+		world.Turns.Step()
+	}
+	world.Turns.RunEnemyPhase()
+	return order
+}
+
+func countTurns(order []*Enemy, enemy *Enemy) int {
+	count := 0
+	for _, e := range order {
+		if e == enemy {
+			count++
+		}
+	}
+	return count
+}
+
+func TestEnemyPhaseInitiativeOrder(t *testing.T) {
+	world := newTestWorld(t, 1, turnTestMap)
+	for _, enemy := range world.Enemies {
+		enemy.Stats = world.Player.Stats
+	}
+	world.Enemies[1].Stats.Dexterity += 2
+	world.Enemies[2].Stats.Dexterity += 1
+
+	// Everyone starts with enough energy for a turn, the faster enemies get to act first
+	order := runEnemyPhase(world)
+	expected := []*Enemy{world.Enemies[1], world.Enemies[2], world.Enemies[0]}
+	if len(order) < len(expected) {
+		t.Fatalf("expected at least %d turns, got %d", len(expected), len(order))
+	}
+	for i, enemy := range expected {
+		if order[i] != enemy {
+			t.Fatalf("turn %d was taken by the wrong enemy", i)
+		}
+	}
+}
+
+func TestEnemyPhaseSpeed(t *testing.T) {
+	world := newTestWorld(t, 1, turnTestMap)
+	fast := world.Enemies[0]
+	normal := world.Enemies[1]
+	slow := world.Enemies[2]
+	fast.Stats.Dexterity = world.Player.Stats.Dexterity * 2
+	fast.Stats.Movement = world.Player.Stats.Movement * 2
+	normal.Stats.Dexterity = world.Player.Stats.Dexterity
+	normal.Stats.Movement = world.Player.Stats.Movement
+	slow.Stats.Dexterity = world.Player.Stats.Dexterity / 2
+	slow.Stats.Movement = world.Player.Stats.Movement / 2
+	// Keep the player alive through all the attacks
+	world.Player.Health = 1e6
+
+	var order []*Enemy
+	const rounds = 12
+	for round := 0; round < rounds; round++ {
+		order = append(order, runEnemyPhase(world)...)
+	}
+
+	// Energy left over from the starting turn can buy one extra turn
+	cases := []struct {
+		name     string
+		enemy    *Enemy
+		expected int
+	}{
+		{"twice as fast", fast, rounds * 2},
+		{"as fast as the player", normal, rounds},
+		{"half as fast", slow, rounds / 2},
+	}
+	for _, c := range cases {
+		if turns := countTurns(order, c.enemy); abs(turns-c.expected) > 1 {
+			t.Errorf("%v enemy took %d turns in %d rounds, expected %d", c.name, turns, rounds, c.expected)
+		}
+	}
+}
+
+func TestTurnOrderPrediction(t *testing.T) {
+	world := newTestWorld(t, 1, turnTestMap)
+	world.Enemies[0].Stats.Dexterity = 30
+
+	predicted := TurnOrder(world.Player, world.Enemies, 12)
+	if predicted[0] != Character(world.Player) {
+		t.Fatal("turn order should start with the player")
+	}
+
+	// Play the predicted turns out and compare
+	actual := []Character{world.Player}
+	for len(actual) < len(predicted) {
+		for _, enemy := range runEnemyPhase(world) {
+			actual = append(actual, enemy)
+		}
+		actual = append(actual, world.Player)
+	}
+	for i := range predicted {
+		if predicted[i] != actual[i] {
+			t.Fatalf("turn %d was predicted wrong", i)
+		}
+	}
+}
+
+func TestEnemyPhaseDeterministic(t *testing.T) {
+	var results [2][]IVector2
+	var health [2]float32
+	for run := range results {
+		world := newTestWorld(t, 42, turnTestMap)
+		for round := 0; round < 10; round++ {
+			world.EndTurn()
+			world.Turns.RunEnemyPhase()
+		}
+		results[run] = enemyPositions(world)
+		health[run] = world.Player.Health
+	}
+
+	for i := range results[0] {
+		if results[0][i] != results[1][i] {
+			t.Fatalf("enemy %d ended at %v and %v", i, results[0][i], results[1][i])
+		}
+	}
+	if health[0] != health[1] {
+		t.Fatalf("player health ended at %v and %v", health[0], health[1])
+	}
+}
+
+func TestEnemyPhaseBoxedInEnemy(t *testing.T) {
+	world := newTestWorld(t, 1, `
+@@@@@@@
+@P_@@@@
+@__@g@@
+@__@@@@
+@@@@@@@
+`)
+
+	world.EndTurn()
+	world.Turns.RunEnemyPhase()
+	if world.Enemies[0].Pos != NewIVector2(4*TILE_SIZE, 2*TILE_SIZE) {
+		t.Error("boxed in enemy moved")
+	}
+}
+
+func TestEnemyPhaseAttacks(t *testing.T) {
+	world := newTestWorld(t, 1, `
+@@@@@
+@Pg_@
+@@@@@
+`)
+
+	world.EndTurn()
+	world.Turns.RunEnemyPhase()
+	var hits, misses int
+	for _, event := range world.Events.Entries {
+		switch event.Kind {
+		case EVENT_PLAYER_HIT:
+			hits++
+		case EVENT_DODGED:
+			misses++
+		}
+	}
+	if hits+misses != 1 {
+		t.Fatalf("expected one attack, got %d hits and %d misses", hits, misses)
+	}
+	if hurt := world.Player.Health < world.Player.MaxHealth(); hurt != (hits == 1) {
+		t.Errorf("%d hits left the player with %v health", hits, world.Player.Health)
+	}
+}
+
+func TestAdjacentEnemyHurtsPlayer(t *testing.T) {
+	world := newTestWorld(t, 1, `
+@@@@@
+@Pg_@
+@@@@@
+`)
+	world.Enemies[0].Stats.Dexterity = 200
+	rollCritical(world)
+	health := world.Player.Health
+
+	world.EndTurn()
+	world.Turns.RunEnemyPhase()
+	hits := 0
+	for _, event := range world.Events.Entries {
+		if event.Kind == EVENT_PLAYER_HIT {
+			hits++
+		}
+	}
+	if hits == 0 || world.Player.Health >= health {
+		t.Errorf("%d hits left the player with %v health out of %v", hits, world.Player.Health, health)
+	}
+}
+
+func TestPlayerDiesOnce(t *testing.T) {
+	world := newTestWorld(t, 1, `
+@@@@@
+@gPg@
+@@@@@
+`)
+	for _, enemy := range world.Enemies {
+		enemy.Stats.Dexterity = 200
+		enemy.Stats.Strength = 50
+	}
+	world.Player.Health = 1
+
+	for round := 0; round < 3; round++ {
+		world.EndTurn()
+		world.Turns.RunEnemyPhase()
+	}
+	// Effects can't kill the player again either
+	world.hurt(world.Player, 10, "poison")
+
+	deaths := 0
+	for _, event := range world.Events.Entries {
+		if event.Kind == EVENT_PLAYER_DIED {
+			deaths++
+		}
+	}
+	if !world.Player.IsDead() || deaths != 1 {
+		t.Errorf("the player died %d times with %v health", deaths, world.Player.Health)
+	}
+}
+
+func TestRngIsReseededEveryRound(t *testing.T) {
+	world := newTestWorld(t, 1, turnTestMap)
+	world.Seed = 1
+	seeds := make(map[int64]bool)
+	for round := 0; round < 5; round++ {
+		world.rng.Int63()
+		world.EndTurn()
+		world.Turns.RunEnemyPhase()
+
+		// Restoring the state never has to skip over the numbers of earlier rounds
+		seed, calls := world.rngSource.State()
+		if calls != 0 || seeds[seed] {
+			t.Fatalf("round %d started from seed %d with %d calls", world.Turns.Round, seed, calls)
+		}
+		seeds[seed] = true
+	}
+}
+
+func TestEnemySightFollowsDigging(t *testing.T) {
+	world := newTestWorld(t, 1, `
+@@@@@@@
+@_P@g_@
+@@@@@@@
+`)
+	world.Player.StartTurn()
+	enemy := world.Enemies[0]
+	if enemy.CanSeePlayer(world) {
+		t.Fatal("the enemy sees through the wall")
+	}
+	sight := enemy.Sight
+	enemy.CanSeePlayer(world)
+	if reflect.ValueOf(enemy.Sight).Pointer() != reflect.ValueOf(sight).Pointer() {
+		t.Error("the sight was computed again without anything changing")
+	}
+
+	if err := world.Execute(NewDigCommand(tilePos(3, 1))); err != nil {
+		t.Fatal(err)
+	}
+	if !enemy.CanSeePlayer(world) {
+		t.Error("the enemy doesn't see through the dug wall")
+	}
+	world.Commands.Undo(world)
+	if enemy.CanSeePlayer(world) {
+		t.Error("the enemy still sees through the wall once the dig is undone")
+	}
+}
@@ -0,0 +1,32 @@
+package sim
+
+import (
+	"fov"
+)
+
+// The player can spot lit tiles and enemy torches further away than their own light reaches
+const SIGHT_RANGE_MULT = 2
+
+func tilePoint(pos IVector2) fov.Point {
+	return fov.Point{X: int(pos.X / TILE_SIZE), Y: int(pos.Y / TILE_SIZE)}
+}
+
+func (world *World) isOpaque(x int, y int) bool {
+	tile, ok := world.GetMapTile(NewIVector2(int32(x)*TILE_SIZE, int32(y)*TILE_SIZE))
+	return !ok || tile.Block
+}
+
+func (world *World) ComputeFOV(pos IVector2, radius uint8) fov.Set {
+	return fov.Compute(tilePoint(pos), int(radius), world.isOpaque)
+}
+
+// The player also sees everything their flares light
+func (world *World) updatePlayerSight() {
+	world.PlayerSight = world.ComputeFOV(world.Player.Pos, world.Player.EffectiveStats().Visibility*SIGHT_RANGE_MULT)
+	for _, flare := range world.Flares {
+		flare.Sight = world.area(flare.Pos, flare.Radius)
+		for point := range flare.Sight {
+			world.PlayerSight[point] = true
+		}
+	}
+}
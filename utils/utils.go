@@ -3,6 +3,7 @@ package utils
 import (
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"io/ioutil"
 	"log"
 	"os"
@@ -16,6 +17,7 @@ const (
 	MAIN_MENU = iota
 	PAUSED    = iota
 	IN_GAME   = iota
+	GAME_OVER = iota
 )
 
 const (
@@ -25,6 +27,7 @@ const (
 	MUSIC      = iota
 	STYLESHEET = iota
 	FONT       = iota
+	LEVEL      = iota
 )
 
 const assetsFolder = "assets/"
@@ -34,22 +37,30 @@ const soundFolder = assetsFolder + "sound/"
 const stylesFolder = assetsFolder + "stylesheets/"
 const fontsFolder = assetsFolder + "fonts/"
 const musicFolder = assetsFolder + "music/"
+const levelsFolder = assetsFolder + "levels/"
+
+const SAVE_FILE = "savegame.json"
+const QUICKSAVE_FILE = "quicksave.json"
 
 type State struct {
 	Loading      bool
 	View         int
 	Settings     Settings
 	RenderAssets *RenderingAssets
+	ActiveSeed   int64
+	// Load the autosave instead of starting a new game
+	ContinueGame bool
 }
 
 type RenderingAssets struct {
 	TileTextures     []rl.Texture2D
-	CharacterSprites []rl.Texture2D
+	CharacterSprites map[string]rl.Texture2D
 	UISprites        []rl.Texture2D
 	MissingTexture   *rl.Texture2D
 	MainFont         rl.Font
 	SecondaryFont    rl.Font
 	TestTextures     TileSet
+	TileSets         map[string]*TileSet
 }
 
 type TileSet struct {
@@ -58,15 +69,16 @@ type TileSet struct {
 	Loaded   bool
 }
 
-func (tileset *TileSet) GetTexture(neighbours uint16) *rl.Texture2D {
+// Missing is returned when the tileset has no texture for the neighbours
+func (tileset *TileSet) GetTexture(neighbours uint16, missing *rl.Texture2D) *rl.Texture2D {
 	if neighbours > 4096 {
-		return appState.RenderAssets.MissingTexture
+		return missing
 	}
 
 	if tex := tileset.Textures[neighbours]; tex.Height != 0 {
 		return &tex
 	} else {
-		return appState.RenderAssets.MissingTexture
+		return missing
 	}
 }
 
@@ -86,7 +98,6 @@ func (ivec IVector2) ToVec2() rl.Vector2 {
 	return rl.Vector2{X: float32(ivec.X), Y: float32(ivec.Y)}
 }
 
-var appState *State
 var DebugMode bool
 
 func InitUtils(state *State, debug bool) {
@@ -94,9 +105,8 @@ func InitUtils(state *State, debug bool) {
 		X: 800,
 		Y: 600,
 	}
-	appState = state
 	DebugMode = debug
-	loadSettingsFile(state.Settings.Resolution != defaultRes)
+	state.Settings.loadSettingsFile(state.Settings.Resolution != defaultRes)
 }
 
 func DebugPrint(v interface{}) {
@@ -119,6 +129,8 @@ func GetAssetPath(asset_type int, path string) string {
 		return stylesFolder + path
 	case FONT:
 		return fontsFolder + path
+	case LEVEL:
+		return levelsFolder + path
 	default:
 		return "Invalid asset type"
 	}
@@ -129,6 +141,13 @@ type Settings struct {
 	Music              bool
 	Resolution         IVector2
 	SelectedResolution int
+	Seed               int64
+	SeedInput          string
+	LevelPath          string
+	Generator          string
+	MapOverrides       map[string]string
+	RecordPath         string
+	ReplayPath         string
 }
 
 type SettingsFile struct {
@@ -149,9 +168,10 @@ var ResolutionList = []string{
 	"3840x2160",
 }
 
-func StringToRes(s string) IVector2 {
+// "Custom" keeps the current resolution
+func StringToRes(s string, current IVector2) IVector2 {
 	if s == "Custom" {
-		return appState.Settings.Resolution
+		return current
 	} else {
 		split := strings.Split(s, "x")
 
@@ -170,38 +190,59 @@ func StringToRes(s string) IVector2 {
 	}
 }
 
+// ParseSeed uses numeric input as is and hashes any other text into a seed.
+// Empty input returns 0, which means a random seed.
+func ParseSeed(s string) int64 {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+
+	if seed, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return seed
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(s))
+	return int64(hash.Sum64())
+}
+
+func SaveExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
 func ResToString(res IVector2) string {
 	return fmt.Sprintf("%dx%d", res.X, res.Y)
 }
 
-func HandleResolutionChange(newRes IVector2) bool {
-	if newRes != appState.Settings.Resolution {
-		appState.Settings.Resolution = newRes
-		rl.SetWindowSize(int(appState.Settings.Resolution.X), int(appState.Settings.Resolution.Y))
-		centerWindow()
-		SaveSettingsFile()
+func (settings *Settings) HandleResolutionChange(newRes IVector2) bool {
+	if newRes != settings.Resolution {
+		settings.Resolution = newRes
+		rl.SetWindowSize(int(settings.Resolution.X), int(settings.Resolution.Y))
+		centerWindow(settings.Resolution)
+		settings.SaveSettingsFile()
 		return true
 	}
 	return false
 }
 
-func centerWindow() {
+func centerWindow(windowRes IVector2) {
 	currMonitor := rl.GetCurrentMonitor()
 	monitorRes := NewIVector2(int32(rl.GetMonitorWidth(currMonitor)), int32(rl.GetMonitorHeight(currMonitor)))
-	windowRes := appState.Settings.Resolution
 	diff := rl.Vector2Subtract(monitorRes.ToVec2(), windowRes.ToVec2())
 	diff = rl.Vector2DivideV(diff, rl.NewVector2(2.0, 2.0))
 	rl.SetWindowPosition(int(diff.X), int(diff.Y))
 }
 
-func SaveSettingsFile() {
-	settings := SettingsFile{
-		Music:            appState.Settings.Music,
-		ResolutionWidth:  int(appState.Settings.Resolution.X),
-		ResolutionHeight: int(appState.Settings.Resolution.Y),
+func (settings *Settings) SaveSettingsFile() {
+	settingsFile := SettingsFile{
+		Music:            settings.Music,
+		ResolutionWidth:  int(settings.Resolution.X),
+		ResolutionHeight: int(settings.Resolution.Y),
 	}
 
-	file, _ := json.MarshalIndent(settings, "", "	")
+	file, _ := json.MarshalIndent(settingsFile, "", "	")
 
 	if f, err := os.OpenFile("settings.json", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755); err != nil {
 		log.Println("Couldn't write settings file")
@@ -211,24 +252,24 @@ func SaveSettingsFile() {
 	}
 }
 
-func loadSettingsFile(overrideRes bool) {
-	var settings SettingsFile
+func (settings *Settings) loadSettingsFile(overrideRes bool) {
+	var settingsFile SettingsFile
 
 	if file, err := ioutil.ReadFile("settings.json"); err == nil {
-		if err = json.Unmarshal(file, &settings); err != nil {
+		if err = json.Unmarshal(file, &settingsFile); err != nil {
 			log.Println("Malformed settings file, rewriting with default settings")
-			SaveSettingsFile()
-			loadSettingsFile(overrideRes)
+			settings.SaveSettingsFile()
+			settings.loadSettingsFile(overrideRes)
 		} else {
-			appState.Settings.Music = settings.Music
+			settings.Music = settingsFile.Music
 			if !overrideRes {
-				newRes := NewIVector2(int32(settings.ResolutionWidth), int32(settings.ResolutionHeight))
-				appState.Settings.Resolution = newRes
+				newRes := NewIVector2(int32(settingsFile.ResolutionWidth), int32(settingsFile.ResolutionHeight))
+				settings.Resolution = newRes
 			}
 		}
 	} else {
 		log.Println("Settings file missing, writing with default settings")
-		SaveSettingsFile()
-		loadSettingsFile(overrideRes)
+		settings.SaveSettingsFile()
+		settings.loadSettingsFile(overrideRes)
 	}
 }